@@ -1,12 +1,26 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap/zapcore"
 
+	"github.com/yizhinailong/demo/gin/internal/auth"
 	"github.com/yizhinailong/demo/gin/internal/config"
+	"github.com/yizhinailong/demo/gin/internal/repository"
 	"github.com/yizhinailong/demo/gin/internal/server/middleware"
+	"github.com/yizhinailong/demo/gin/pkg/logger"
 
 	_ "github.com/yizhinailong/demo/gin/internal/server/handler"
 
@@ -14,15 +28,122 @@ import (
 )
 
 func main() {
-	cfg := config.GetConfig()
+	fs := pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	config.RegisterFlags(fs)
+	printConfig := fs.Bool("print-config", false, "print the effective config with secrets redacted and exit")
+	_ = fs.Parse(os.Args[1:])
 
+	cfg, err := config.Init()
+	if *printConfig && cfg != nil {
+		out, _ := json.MarshalIndent(cfg.Redacted(), "", "  ")
+		fmt.Println(string(out))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *printConfig {
+		return
+	}
+	if err := logger.Init(&logger.Config{
+		Level:              cfg.Log.Level,
+		Console:            cfg.Log.Console,
+		ConsoleFormat:      cfg.Log.Format,
+		ConsoleOutput:      cfg.Log.Output,
+		FilePath:           cfg.Log.File.Path,
+		MaxSize:            cfg.Log.File.MaxSize,
+		MaxBackups:         cfg.Log.File.MaxBackups,
+		MaxAge:             cfg.Log.File.MaxAge,
+		Compress:           cfg.Log.File.Compress,
+		RedactFields:       cfg.Log.Redact.Fields,
+		RedactPatterns:     cfg.Log.Redact.Patterns,
+		SamplingInitial:    cfg.Log.Sampling.Initial,
+		SamplingThereafter: cfg.Log.Sampling.Thereafter,
+		SamplingTick:       config.ParseDuration(cfg.Log.Sampling.Tick, time.Second),
+	}); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to initialize logger:", err)
+		os.Exit(1)
+	}
+	// Refuse to start with JWT keys that cannot be loaded
+	if cfg.Auth.Enabled && cfg.Auth.JWT.Configured() {
+		if _, err := auth.NewVerifier(cfg.Auth.JWT); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	// Route slog through zap so every log line shares one format and destination
+	slog.SetDefault(slog.New(logger.NewSlogHandler(nil)))
+
+	// Follow log.level on reload; temporary admin overrides still revert to it
+	config.Subscribe(func(old, new *config.Config) {
+		if old != nil && old.Log.Level == new.Log.Level {
+			return
+		}
+		if lvl, err := zapcore.ParseLevel(new.Log.Level); err == nil {
+			logger.SetLevel(lvl)
+		}
+	})
+	config.Watch()
+
+	gin.SetMode(cfg.Server.Mode)
 	r := gin.New()
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		slog.Error("Invalid trusted proxies", "error", err)
+		os.Exit(2)
+	}
 	middleware.Use(r)
 	router.SetupRoutes(r)
 
-	// slog.Info("Web server listening address http://localhost:" + cfg.Server.Port)
+	srv := &http.Server{
+		Addr:           ":" + cfg.Server.Port,
+		Handler:        r,
+		ReadTimeout:    config.ParseDuration(cfg.Server.ReadTimeout, 30*time.Second),
+		WriteTimeout:   config.ParseDuration(cfg.Server.WriteTimeout, 30*time.Second),
+		MaxHeaderBytes: cfg.Server.MaxHeaderBytes,
+	}
 
-	if err := r.Run(":" + cfg.Server.Port); err != nil {
-		slog.Error("Error starting server error " + err.Error())
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Keep retrying databases that are not reachable yet in the background
+	repository.StartReconnect(ctx)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Web server listening", "addr", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+
+	exitCode := 0
+	select {
+	case err := <-serveErr:
+		slog.Error("Error starting server", "error", err)
+		exitCode = 1
+	case <-ctx.Done():
+		slog.Info("Shutdown signal received, draining connections")
 	}
+	stop()
+
+	// Give in-flight requests the grace period to finish before closing the databases
+	grace := config.ParseDuration(cfg.Server.ShutdownTimeout, 15*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Server forced to shutdown", "error", err)
+		exitCode = 1
+	}
+
+	if err := repository.Close(); err != nil {
+		slog.Error("Failed to close database connections", "error", err)
+		exitCode = 1
+	}
+
+	slog.Info("Server stopped")
+	logger.Sync()
+	os.Exit(exitCode)
 }
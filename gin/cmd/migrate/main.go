@@ -0,0 +1,58 @@
+// Command migrate creates and upgrades the database schema. Run it before
+// starting a new version of the API server, which no longer changes the
+// schema itself:
+//
+//	migrate [flags] [mysql] [postgres]
+//
+// Without arguments both databases are migrated. It takes the same config
+// flags, files and APP_ environment variables as the API server.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/yizhinailong/demo/gin/internal/config"
+	"github.com/yizhinailong/demo/gin/internal/repository"
+)
+
+func main() {
+	fs := pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	config.RegisterFlags(fs)
+	_ = fs.Parse(os.Args[1:])
+
+	if _, err := config.Init(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	databases := fs.Args()
+	if len(databases) == 0 {
+		databases = []string{"mysql", "postgres"}
+	}
+
+	// Index builds on large tables may take a while; only a signal stops them
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	exitCode := 0
+	for _, name := range databases {
+		start := time.Now()
+		if err := repository.Migrate(ctx, name); err != nil {
+			slog.Error("Migration failed", "database", name, "error", err)
+			exitCode = 1
+			continue
+		}
+		slog.Info("Schema up to date", "database", name, "took", time.Since(start))
+	}
+
+	stop()
+	os.Exit(exitCode)
+}
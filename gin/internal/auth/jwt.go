@@ -0,0 +1,167 @@
+// Package auth verifies bearer tokens and carries the authenticated caller
+// through gin and context.Context.
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/yizhinailong/demo/gin/internal/config"
+)
+
+// ErrInvalidToken wraps every reason a token is rejected
+var ErrInvalidToken = errors.New("invalid token")
+
+// Verifier checks JWT signatures and standard claims without any network
+// access: all keys come from configuration.
+type Verifier struct {
+	hmacSecret []byte
+	rsaKey     *rsa.PublicKey            // from rs256_public_key
+	jwks       map[string]*rsa.PublicKey // by kid
+	parser     *jwt.Parser
+}
+
+// claims are the registered claims plus the scope formats we accept:
+// "scope" as a space separated string (RFC 8693) or "scp" as a list.
+type claims struct {
+	jwt.RegisteredClaims
+	Scope string   `json:"scope"`
+	Scp   []string `json:"scp"`
+}
+
+// NewVerifier builds a Verifier from the auth.jwt settings
+func NewVerifier(cfg config.JWTConfig) (*Verifier, error) {
+	v := &Verifier{}
+	var methods []string
+
+	if cfg.HS256Secret != "" {
+		v.hmacSecret = []byte(cfg.HS256Secret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.RS256PublicKey != "" {
+		key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(cfg.RS256PublicKey))
+		if err != nil {
+			return nil, fmt.Errorf("auth.jwt.rs256_public_key: %w", err)
+		}
+		v.rsaKey = key
+	}
+	if cfg.JWKS != "" {
+		keys, err := parseJWKS([]byte(cfg.JWKS))
+		if err != nil {
+			return nil, fmt.Errorf("auth.jwt.jwks: %w", err)
+		}
+		v.jwks = keys
+	}
+	if v.rsaKey != nil || len(v.jwks) > 0 {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("auth.jwt: no verification key configured")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(config.ParseDuration(cfg.Leeway, 30*time.Second)),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(opts...)
+
+	return v, nil
+}
+
+// Verify validates token and returns its subject and scopes
+func (v *Verifier) Verify(token string) (*Principal, error) {
+	var c claims
+	if _, err := v.parser.ParseWithClaims(token, &c, v.key); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if c.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub claim", ErrInvalidToken)
+	}
+
+	scopes := strings.Fields(c.Scope)
+	scopes = append(scopes, c.Scp...)
+	return &Principal{Subject: c.Subject, Scopes: scopes}, nil
+}
+
+// key selects the verification key for the token's algorithm and kid
+func (v *Verifier) key(t *jwt.Token) (any, error) {
+	switch t.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return v.hmacSecret, nil
+	case jwt.SigningMethodRS256.Alg():
+		if kid, _ := t.Header["kid"].(string); kid != "" {
+			if key, ok := v.jwks[kid]; ok {
+				return key, nil
+			}
+			if v.rsaKey == nil {
+				return nil, fmt.Errorf("unknown key id %q", kid)
+			}
+		}
+		if v.rsaKey != nil {
+			return v.rsaKey, nil
+		}
+		// A JWKS with a single key does not need a kid in the token
+		if len(v.jwks) == 1 {
+			for _, key := range v.jwks {
+				return key, nil
+			}
+		}
+		return nil, errors.New("token has no kid matching the JWKS")
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+}
+
+// parseJWKS reads the RSA signature keys of a JSON Web Key Set
+func parseJWKS(data []byte) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			Alg string `json:"alg"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for i, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != "RS256") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: invalid modulus: %w", i, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: invalid exponent: %w", i, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no RS256 signing keys found")
+	}
+	return keys, nil
+}
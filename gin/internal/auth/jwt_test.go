@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yizhinailong/demo/gin/internal/config"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	require.NoError(t, err)
+	return s
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   "user-1",
+		"iss":   "https://issuer.test",
+		"aud":   "demo-api",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "users:read users:write",
+	}
+}
+
+func publicKeyPEM(t *testing.T, key *rsa.PrivateKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func jwks(t *testing.T, keys map[string]*rsa.PrivateKey) string {
+	t.Helper()
+	var set struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestVerifier(t *testing.T) {
+	keyA, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyB, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	v, err := NewVerifier(config.JWTConfig{
+		Issuer:      "https://issuer.test",
+		Audience:    "demo-api",
+		Leeway:      "5s",
+		HS256Secret: testSecret,
+		JWKS:        jwks(t, map[string]*rsa.PrivateKey{"a": keyA, "b": keyB}),
+	})
+	require.NoError(t, err)
+
+	t.Run("hs256", func(t *testing.T) {
+		p, err := v.Verify(sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", validClaims()))
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", p.Subject)
+		assert.Equal(t, []string{"users:read", "users:write"}, p.Scopes)
+		assert.True(t, p.HasScopes("users:read"))
+		assert.False(t, p.HasScopes("users:read", "admin"))
+	})
+
+	t.Run("rs256 from jwks by kid", func(t *testing.T) {
+		claims := validClaims()
+		delete(claims, "scope")
+		claims["scp"] = []string{"users:read"}
+
+		p, err := v.Verify(sign(t, jwt.SigningMethodRS256, keyB, "b", claims))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"users:read"}, p.Scopes)
+	})
+
+	rejected := map[string]string{
+		"wrong kid":       sign(t, jwt.SigningMethodRS256, keyA, "b", validClaims()),
+		"unknown kid":     sign(t, jwt.SigningMethodRS256, keyA, "c", validClaims()),
+		"missing kid":     sign(t, jwt.SigningMethodRS256, keyA, "", validClaims()),
+		"wrong secret":    sign(t, jwt.SigningMethodHS256, []byte("another-secret-another-secret-xx"), "", validClaims()),
+		"alg none":        sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", validClaims()),
+		"unsupported alg": sign(t, jwt.SigningMethodHS512, []byte(testSecret), "", validClaims()),
+		"malformed":       "not.a.jwt",
+	}
+	for name, field := range map[string]struct {
+		key   string
+		value any
+	}{
+		"expired":        {"exp", time.Now().Add(-time.Minute).Unix()},
+		"not yet valid":  {"nbf", time.Now().Add(time.Minute).Unix()},
+		"wrong issuer":   {"iss", "https://other.test"},
+		"wrong audience": {"aud", "other-api"},
+		"no subject":     {"sub", ""},
+	} {
+		claims := validClaims()
+		claims[field.key] = field.value
+		rejected[name] = sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims)
+	}
+	noExp := validClaims()
+	delete(noExp, "exp")
+	rejected["no expiry"] = sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", noExp)
+
+	for name, token := range rejected {
+		t.Run("rejects "+name, func(t *testing.T) {
+			_, err := v.Verify(token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+
+	t.Run("leeway tolerates skew", func(t *testing.T) {
+		claims := validClaims()
+		claims["exp"] = time.Now().Add(-2 * time.Second).Unix()
+		_, err := v.Verify(sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims))
+		assert.NoError(t, err)
+	})
+
+	t.Run("rs256 from pem", func(t *testing.T) {
+		pv, err := NewVerifier(config.JWTConfig{RS256PublicKey: publicKeyPEM(t, keyA)})
+		require.NoError(t, err)
+
+		_, err = pv.Verify(sign(t, jwt.SigningMethodRS256, keyA, "", validClaims()))
+		assert.NoError(t, err)
+		// HS256 is not accepted without a secret, even signed with the public key bytes
+		_, err = pv.Verify(sign(t, jwt.SigningMethodHS256, []byte(publicKeyPEM(t, keyA)), "", validClaims()))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
+
+func TestNewVerifier_Errors(t *testing.T) {
+	_, err := NewVerifier(config.JWTConfig{})
+	assert.Error(t, err)
+
+	_, err = NewVerifier(config.JWTConfig{RS256PublicKey: "not pem"})
+	assert.ErrorContains(t, err, "rs256_public_key")
+
+	_, err = NewVerifier(config.JWTConfig{JWKS: `{"keys":[{"kty":"EC","kid":"x"}]}`})
+	assert.ErrorContains(t, err, "jwks")
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yizhinailong/demo/gin/internal/model"
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject string
+	Scopes  []string
+	// APIKey marks service clients authenticated by X-API-Key. They have no
+	// user record, so their scopes alone decide what they may do.
+	APIKey bool
+}
+
+// HasScopes reports whether the principal was granted every scope in required
+func (p *Principal) HasScopes(required ...string) bool {
+	for _, scope := range required {
+		if !slices.Contains(p.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+type ctxKey struct{}
+
+// ginKey stores the principal in the gin context
+const ginKey = "auth.principal"
+
+// WithPrincipal returns a copy of ctx carrying p
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// FromContext returns the principal stored by WithPrincipal
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(ctxKey{}).(*Principal)
+	return p, ok
+}
+
+// SetPrincipal stores p in both the gin context and the request context, so
+// handlers and the services they call see the same caller.
+func SetPrincipal(c *gin.Context, p *Principal) {
+	c.Set(ginKey, p)
+	c.Request = c.Request.WithContext(WithPrincipal(c.Request.Context(), p))
+}
+
+// GetPrincipal returns the principal of the current request, if any
+func GetPrincipal(c *gin.Context) (*Principal, bool) {
+	if v, ok := c.Get(ginKey); ok {
+		p, ok := v.(*Principal)
+		return p, ok
+	}
+	return nil, false
+}
+
+// ErrInvalidAPIKey is returned for unknown, revoked or expired API keys
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// KeyAuthenticator resolves an API key to its principal
+type KeyAuthenticator interface {
+	AuthenticateKey(ctx context.Context, key string) (*Principal, error)
+}
+
+// RoleResolver looks up the role of a principal; an empty role grants nothing
+type RoleResolver interface {
+	RoleOf(ctx context.Context, p *Principal) (model.Role, error)
+}
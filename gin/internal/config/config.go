@@ -3,120 +3,795 @@ package config
 import (
 	"fmt"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server   ServerConfig   `toml:"server"`
-	Log      LogConfig      `toml:"log"`
-	Database DatabaseConfig `toml:"database"`
+	App       AppConfig       `toml:"app"`
+	Server    ServerConfig    `toml:"server"`
+	Log       LogConfig       `toml:"log"`
+	Database  DatabaseConfig  `toml:"database"`
+	Health    HealthConfig    `toml:"health"`
+	Admin     AdminConfig     `toml:"admin"`
+	CORS      CORSConfig      `toml:"cors"`
+	RateLimit RateLimitConfig `toml:"rate_limit"`
+	Auth      AuthConfig      `toml:"auth"`
+	// Features are named toggles that can be flipped at runtime via reload;
+	// Viper lower-cases the keys, so look them up through Feature
+	Features map[string]bool `toml:"features"`
+}
+
+// Feature reports whether the named feature toggle is enabled. Names are
+// case-insensitive, matching how Viper stores them.
+func (c *Config) Feature(name string) bool {
+	return c.Features[strings.ToLower(name)]
+}
+
+type AppConfig struct {
+	// Profile names the deployment environment, e.g. "dev" or "prod"
+	Profile string `toml:"profile"`
+}
+
+// IsProduction reports whether the production profile is active
+func (c *Config) IsProduction() bool {
+	return c.App.Profile == "prod" || c.App.Profile == "production"
 }
 
 type ServerConfig struct {
-	Port           string `toml:"port"`
-	ReadTimeout    string `toml:"read_timeout"`
-	WriteTimeout   string `toml:"write_timeout"`
-	MaxHeaderBytes int    `toml:"max_header_bytes"`
+	Port            string `toml:"port"`
+	ReadTimeout     string `toml:"read_timeout"`
+	WriteTimeout    string `toml:"write_timeout"`
+	MaxHeaderBytes  int    `toml:"max_header_bytes"`
+	ShutdownTimeout string `toml:"shutdown_timeout"`
+	// Mode is the gin mode: "debug", "release" or "test"
+	Mode string `toml:"mode"`
+	// DebugEndpoints mounts /debug/pprof
+	DebugEndpoints bool `toml:"debug_endpoints"`
+	// TrustedProxies may set X-Forwarded-For; the client IP of requests from
+	// any other address is the connection's remote address
+	TrustedProxies []string `toml:"trusted_proxies"`
+	// MaxBodyBytes rejects larger request bodies with 413; 0 disables the limit
+	MaxBodyBytes int64 `toml:"max_body_bytes"`
+	// RequestTimeout bounds the context handed to services, so database
+	// queries are cancelled once it expires; "0s" disables it
+	RequestTimeout string        `toml:"request_timeout"`
+	Routes         []RouteLimits `toml:"routes"`
+	// ErrorFormat is "envelope" for the dto.Response body or "problem" for
+	// RFC 7807 application/problem+json
+	ErrorFormat string `toml:"error_format"`
+	// ProblemTypeBase prefixes the error code to form a problem's "type" URI;
+	// empty sends "about:blank"
+	ProblemTypeBase string `toml:"problem_type_base"`
+}
+
+// RouteLimits overrides MaxBodyBytes and RequestTimeout for one route; zero
+// values keep the server-wide setting
+type RouteLimits struct {
+	Method         string `toml:"method"`
+	Route          string `toml:"route"`
+	MaxBodyBytes   int64  `toml:"max_body_bytes"`
+	RequestTimeout string `toml:"request_timeout"`
+}
+
+// Limits returns the body limit and request timeout that apply to a route,
+// matched by method and gin route template
+func (s ServerConfig) Limits(method, route string) (int64, time.Duration) {
+	maxBody := s.MaxBodyBytes
+	timeout := ParseDuration(s.RequestTimeout, 0)
+	for _, r := range s.Routes {
+		if r.Route != route || !strings.EqualFold(r.Method, method) {
+			continue
+		}
+		if r.MaxBodyBytes > 0 {
+			maxBody = r.MaxBodyBytes
+		}
+		if r.RequestTimeout != "" {
+			timeout = ParseDuration(r.RequestTimeout, timeout)
+		}
+	}
+	return maxBody, timeout
 }
 
 type LogConfig struct {
-	Level  string `toml:"level"`
-	Format string `toml:"format"`
-	Output string `toml:"output"`
+	Level string `toml:"level"`
+	// LevelResetAfter is how long a level changed via /admin/log/level lasts
+	// unless the request asks for another duration, which may not exceed
+	// LevelMaxDuration
+	LevelResetAfter  string `toml:"level_reset_after"`
+	LevelMaxDuration string `toml:"level_max_duration"`
+	// Console enables terminal output in Format ("json" or "console") on
+	// Output ("stdout" or "stderr")
+	Console bool          `toml:"console"`
+	Format  string        `toml:"format"`
+	Output  string        `toml:"output"`
+	File    LogFileConfig `toml:"file"`
+	Redact  RedactConfig  `toml:"redact"`
+	// Sampling thins out repeated log lines; Initial 0 disables it
+	Sampling LogSamplingConfig `toml:"sampling"`
+	Access   AccessLogConfig   `toml:"access"`
+}
+
+// LogSamplingConfig mirrors zap sampling: per Tick, the first Initial entries
+// with the same level and message are logged, then every Thereafter-th one.
+type LogSamplingConfig struct {
+	Initial    int    `toml:"initial"`
+	Thereafter int    `toml:"thereafter"`
+	Tick       string `toml:"tick"`
+}
+
+// AccessLogConfig filters the HTTP access log. Skip and Sample only apply to
+// successful requests; 4xx/5xx responses and slow requests are always logged.
+// Routes are gin route templates such as "/users/:id".
+type AccessLogConfig struct {
+	Skip   []string      `toml:"skip"`
+	Sample []RouteSample `toml:"sample"`
+	// SlowThreshold logs a warning for requests slower than this; empty disables it
+	SlowThreshold string `toml:"slow_threshold"`
+}
+
+// RouteSample logs one in Every successful requests to Route
+type RouteSample struct {
+	Route string `toml:"route"`
+	Every int    `toml:"every"`
+}
+
+// RedactConfig extends the built-in log redaction rules (passwords, tokens,
+// Authorization headers, emails) with extra field names and regexps.
+type RedactConfig struct {
+	Fields   []string `toml:"fields"`
+	Patterns []string `toml:"patterns"`
+}
+
+// LogFileConfig configures the rotated JSON log file; empty Path disables it.
+type LogFileConfig struct {
+	Path       string `toml:"path"`
+	MaxSize    int    `toml:"max_size"` // megabytes
+	MaxBackups int    `toml:"max_backups"`
+	MaxAge     int    `toml:"max_age"` // days
+	Compress   bool   `toml:"compress"`
+}
+
+// HealthConfig controls the readiness probe.
+type HealthConfig struct {
+	// Required lists the backends ("mysql", "postgres") that must be
+	// reachable for /readyz to report ready.
+	Required []string `toml:"required"`
+	Timeout  string   `toml:"timeout"`
+}
+
+// AdminConfig protects the /admin endpoints; they are disabled without a token.
+type AdminConfig struct {
+	Token     string `toml:"token"`
+	TokenFile string `toml:"token_file"`
+}
+
+// CORSConfig is the default cross-origin policy. An empty AllowOrigins list
+// disables CORS, so browsers reject cross-origin requests. Origins may use
+// "*" wildcards such as "https://*.example.com".
+type CORSConfig struct {
+	AllowOrigins     []string `toml:"allow_origins"`
+	AllowMethods     []string `toml:"allow_methods"`
+	AllowHeaders     []string `toml:"allow_headers"`
+	ExposeHeaders    []string `toml:"expose_headers"`
+	AllowCredentials bool     `toml:"allow_credentials"`
+	MaxAge           string   `toml:"max_age"`
+	// Groups override the policy for paths under a prefix; the longest
+	// matching prefix wins.
+	Groups []CORSGroup `toml:"groups"`
+}
+
+// CORSGroup overrides CORSConfig for one route group. Unset fields inherit
+// the default policy; an explicit empty allow_origins disables CORS.
+type CORSGroup struct {
+	Prefix           string   `toml:"prefix"`
+	AllowOrigins     []string `toml:"allow_origins"`
+	AllowMethods     []string `toml:"allow_methods"`
+	AllowHeaders     []string `toml:"allow_headers"`
+	ExposeHeaders    []string `toml:"expose_headers"`
+	AllowCredentials *bool    `toml:"allow_credentials"`
+	MaxAge           string   `toml:"max_age"`
+}
+
+// Policy returns the CORS settings for group g, falling back to c
+func (c CORSConfig) Policy(g CORSGroup) CORSConfig {
+	p := c
+	p.Groups = nil
+	if g.AllowOrigins != nil {
+		p.AllowOrigins = g.AllowOrigins
+	}
+	if g.AllowMethods != nil {
+		p.AllowMethods = g.AllowMethods
+	}
+	if g.AllowHeaders != nil {
+		p.AllowHeaders = g.AllowHeaders
+	}
+	if g.ExposeHeaders != nil {
+		p.ExposeHeaders = g.ExposeHeaders
+	}
+	if g.AllowCredentials != nil {
+		p.AllowCredentials = *g.AllowCredentials
+	}
+	if g.MaxAge != "" {
+		p.MaxAge = g.MaxAge
+	}
+	return p
+}
+
+// AuthConfig controls bearer token authentication. While disabled, routes
+// that declare scopes are served anonymously.
+type AuthConfig struct {
+	Enabled bool         `toml:"enabled"`
+	JWT     JWTConfig    `toml:"jwt"`
+	APIKeys APIKeyConfig `toml:"api_keys"`
+	Users   UsersConfig  `toml:"users"`
+}
+
+// UsersConfig locates the user records that roles are read from. A JWT
+// subject is the ID of a user in Database.
+type UsersConfig struct {
+	// Database holds the users table: "mysql" or "postgres"
+	Database string `toml:"database"`
+}
+
+// APIKeyConfig configures X-API-Key authentication for service clients
+type APIKeyConfig struct {
+	// Database holds the api_keys table: "mysql" or "postgres"
+	Database string `toml:"database"`
+}
+
+// JWTConfig holds the keys used to verify JWTs offline. HS256 tokens are
+// checked against HS256Secret, RS256 tokens against RS256PublicKey (PEM) or
+// the key in JWKS whose "kid" matches the token header.
+type JWTConfig struct {
+	// Issuer and Audience, when set, must match the iss and aud claims
+	Issuer   string `toml:"issuer"`
+	Audience string `toml:"audience"`
+	// Leeway tolerates clock skew when checking exp and nbf
+	Leeway             string `toml:"leeway"`
+	HS256Secret        string `toml:"hs256_secret"`
+	HS256SecretFile    string `toml:"hs256_secret_file"`
+	RS256PublicKey     string `toml:"rs256_public_key"`
+	RS256PublicKeyFile string `toml:"rs256_public_key_file"`
+	// JWKS is a JSON Web Key Set document; JWKSFile loads it from disk
+	JWKS     string `toml:"jwks"`
+	JWKSFile string `toml:"jwks_file"`
+}
+
+// Configured reports whether any JWT verification key is set; without one
+// only API keys are accepted.
+func (c JWTConfig) Configured() bool {
+	return c.HS256Secret != "" || c.RS256PublicKey != "" || c.JWKS != ""
+}
+
+// RateLimitConfig limits requests with token buckets: every client per IP,
+// authenticated callers additionally per subject. Requests refill at Requests
+// per Period, up to Burst at once.
+type RateLimitConfig struct {
+	Enabled  bool   `toml:"enabled"`
+	Requests int    `toml:"requests"`
+	Period   string `toml:"period"`
+	// Burst defaults to Requests
+	Burst int `toml:"burst"`
+	// Routes get their own, separate buckets
+	Routes []RouteRateLimit `toml:"routes"`
+}
+
+// RouteRateLimit overrides the limit for one route, e.g. POST /users
+type RouteRateLimit struct {
+	Method   string `toml:"method"`
+	Route    string `toml:"route"`
+	Requests int    `toml:"requests"`
+	Period   string `toml:"period"`
+	Burst    int    `toml:"burst"`
 }
 
 type DatabaseConfig struct {
 	MySQL    MySQLConfig    `toml:"mysql"`
 	Postgres PostgresConfig `toml:"postgres"`
+	Retry    RetryConfig    `toml:"retry"`
+}
+
+// RetryConfig controls the exponential backoff used while a database is unreachable.
+type RetryConfig struct {
+	InitialInterval string  `toml:"initial_interval"`
+	MaxInterval     string  `toml:"max_interval"`
+	Multiplier      float64 `toml:"multiplier"`
 }
 
 type MySQLConfig struct {
+	// DSN, when set, is used as-is instead of building one from the fields below
+	DSN      string `toml:"dsn"`
 	Host     string `toml:"host"`
 	Port     int    `toml:"port"`
 	Name     string `toml:"name"`
 	User     string `toml:"user"`
 	Password string `toml:"password"`
+	// PasswordFile, when set, replaces Password with the file's content
+	PasswordFile string `toml:"password_file"`
+	// TLS is the driver's tls parameter: "true", "false", "skip-verify" or "preferred"
+	TLS            string `toml:"tls"`
+	ConnectTimeout string `toml:"connect_timeout"`
+	QueryTimeout   string `toml:"query_timeout"`
+	// Params are appended to the DSN; note that Viper lower-cases the keys
+	Params map[string]string `toml:"params"`
+	Pool   PoolConfig        `toml:"pool"`
 }
 
 type PostgresConfig struct {
+	// DSN, when set, is used as-is instead of building one from the fields below
+	DSN      string `toml:"dsn"`
 	Host     string `toml:"host"`
 	Port     int    `toml:"port"`
 	Name     string `toml:"name"`
 	User     string `toml:"user"`
 	Password string `toml:"password"`
+	// PasswordFile, when set, replaces Password with the file's content
+	PasswordFile string `toml:"password_file"`
+	SSLMode      string `toml:"sslmode"`
+	SSLRootCert  string `toml:"sslrootcert"`
+	// ConnectTimeout is rounded to whole seconds, as lib/pq expects
+	ConnectTimeout string `toml:"connect_timeout"`
+	QueryTimeout   string `toml:"query_timeout"`
+	// Params are appended to the DSN as extra connection/runtime parameters
+	Params map[string]string `toml:"params"`
+	Pool   PoolConfig        `toml:"pool"`
+}
+
+// PoolConfig holds the database/sql connection pool settings.
+type PoolConfig struct {
+	MaxOpenConns    int    `toml:"max_open_conns"`
+	MaxIdleConns    int    `toml:"max_idle_conns"`
+	ConnMaxLifetime string `toml:"conn_max_lifetime"`
+	ConnMaxIdleTime string `toml:"conn_max_idle_time"`
 }
 
 var (
-	Cfg  *Config
-	once sync.Once
-	Err  error
+	// current holds the active snapshot; reloads swap it, never mutate it
+	current atomic.Pointer[Config]
+	// source holds the Viper instances of the initial load, watched by Watch
+	source sources
+	once   sync.Once
+	Err    error
 )
 
-func GetConfig() *Config {
+// Init loads and validates the configuration on first use; later calls
+// return the same result. Callers that can refuse to start should use it
+// instead of GetConfig.
+func Init() (*Config, error) {
 	once.Do(func() {
-		Cfg, Err = Load()
-		if Err != nil {
-			slog.Error("Failed to load config", "error", Err)
+		var cfg *Config
+		cfg, source, Err = load()
+		if Err == nil {
+			Err = cfg.Validate()
 		}
+		current.Store(cfg)
 	})
 
-	return Cfg
+	return current.Load(), Err
+}
+
+// GetConfig returns the current configuration snapshot. The snapshot is
+// shared between goroutines and must be treated as read-only.
+func GetConfig() *Config {
+	if cfg := current.Load(); cfg != nil {
+		return cfg
+	}
+
+	cfg, _ := Init()
+	return cfg
+}
+
+// EnvPrefix prefixes every environment override, e.g. APP_DATABASE_MYSQL_PASSWORD
+// overrides database.mysql.password.
+const EnvPrefix = "APP"
+
+// Profiles understood by setDefaults; other names get the dev defaults
+const (
+	ProfileDev  = "dev"
+	ProfileTest = "test"
+	ProfileProd = "prod"
+)
+
+// flagKeys maps the command-line flags to the config keys they override
+var flagKeys = map[string]string{
+	"port":          "server.port",
+	"log-level":     "log.level",
+	"log-format":    "log.format",
+	"mysql-host":    "database.mysql.host",
+	"mysql-port":    "database.mysql.port",
+	"mysql-dsn":     "database.mysql.dsn",
+	"postgres-host": "database.postgres.host",
+	"postgres-port": "database.postgres.port",
+	"postgres-dsn":  "database.postgres.dsn",
 }
 
+// flags is the flag set registered through RegisterFlags, if any
+var flags *pflag.FlagSet
+
+// RegisterFlags defines the flags understood by Load on fs. The caller is
+// responsible for parsing fs before the config is first loaded.
+func RegisterFlags(fs *pflag.FlagSet) {
+	fs.String("config", "", "path to the config file (env "+EnvPrefix+"_CONFIG)")
+	fs.String("profile", "", "config profile layered over the base file (env "+EnvPrefix+"_PROFILE)")
+	fs.String("port", "", "HTTP listen port")
+	fs.String("log-level", "", "log level: debug, info, warn or error")
+	fs.String("log-format", "", "log format: json or console")
+	fs.String("mysql-host", "", "MySQL host")
+	fs.Int("mysql-port", 0, "MySQL port")
+	fs.String("mysql-dsn", "", "full MySQL DSN, overriding the individual settings")
+	fs.String("postgres-host", "", "PostgreSQL host")
+	fs.Int("postgres-port", 0, "PostgreSQL port")
+	fs.String("postgres-dsn", "", "full PostgreSQL DSN, overriding the individual settings")
+
+	flags = fs
+}
+
+// Load reads the configuration. Sources are applied in the following order of
+// precedence, highest first:
+//
+//  1. command-line flags registered with RegisterFlags (only when set)
+//  2. APP_-prefixed environment variables, with dots replaced by underscores
+//  3. config.<profile>.toml next to the base file, if it exists
+//  4. the base config file: --config, APP_CONFIG, or config/config.toml
+//  5. the built-in defaults from setDefaults, some of which depend on the profile
+//
+// A "<key>_file" secret ranks with the source that set it. The profile comes
+// from --profile or APP_PROFILE and defaults to "dev"; debug endpoints are
+// only on by default when "dev" was chosen explicitly.
 func Load() (*Config, error) {
+	cfg, _, err := load()
+	return cfg, err
+}
+
+// sources are the Viper instances behind a load, kept so Watch can follow them
+type sources struct {
+	base    *viper.Viper
+	profile *viper.Viper // nil when there is no profile file
+}
+
+func load() (*Config, sources, error) {
 	cfg := &Config{}
+	profile, explicit := activeProfile()
 
 	// Initialize Viper
 	v := viper.New()
 
-	v.SetConfigName("config")
-	v.SetConfigType("toml")
-	v.AddConfigPath("config/")
+	if path := configFile(); path != "" {
+		v.SetConfigFile(path)
+	} else {
+		v.SetConfigName("config")
+		v.SetConfigType("toml")
+		v.AddConfigPath("config/")
+	}
+
+	// Set default values; every key needs one so that AutomaticEnv can see it
+	setDefaults(v, profile, explicit)
 
-	// Set default values
-	setDefaults(v)
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	if flags != nil {
+		for name, key := range flagKeys {
+			if err := v.BindPFlag(key, flags.Lookup(name)); err != nil {
+				return nil, sources{}, fmt.Errorf("unable to bind flag --%s: %w", name, err)
+			}
+		}
+	}
 
 	// Read configuration file
 	if err := v.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
 			slog.Info("Config file config.toml not found, using defaults and environment variables")
 		} else {
-			return nil, fmt.Errorf("error reading config file: %w", err)
+			return nil, sources{}, fmt.Errorf("error reading config file: %w", err)
+		}
+	}
+
+	// Layer the profile file over the base file
+	pv, err := readProfileFile(v, profile)
+	if err != nil {
+		return nil, sources{}, err
+	}
+	if pv != nil {
+		if err := v.MergeConfigMap(pv.AllSettings()); err != nil {
+			return nil, sources{}, fmt.Errorf("error merging %s: %w", pv.ConfigFileUsed(), err)
+		}
+	}
+
+	// The profile is fixed before any file is read, so pin it
+	v.Set("app.profile", profile)
+
+	if err := resolveFileSecrets(v, pv); err != nil {
+		return nil, sources{}, err
+	}
+
+	// Unmarshal configuration into struct, matching keys by the toml tags
+	if err := v.Unmarshal(cfg, func(dc *mapstructure.DecoderConfig) {
+		dc.TagName = "toml"
+	}); err != nil {
+		return nil, sources{}, fmt.Errorf("unable to decode config: %w", err)
+	}
+
+	return cfg, sources{base: v, profile: pv}, nil
+}
+
+// activeProfile returns the profile selected by flag or environment, and
+// whether one was selected at all
+func activeProfile() (string, bool) {
+	if flags != nil {
+		if f := flags.Lookup("profile"); f != nil && f.Changed {
+			return f.Value.String(), true
+		}
+	}
+
+	if profile := os.Getenv(EnvPrefix + "_PROFILE"); profile != "" {
+		return profile, true
+	}
+
+	return ProfileDev, false
+}
+
+// readProfileFile reads config.<profile>.toml from the directory of the base
+// file, or from config/ when no base file was found. A missing file is not
+// an error.
+func readProfileFile(base *viper.Viper, profile string) (*viper.Viper, error) {
+	dir, ext := "config", ".toml"
+	if used := base.ConfigFileUsed(); used != "" {
+		dir, ext = filepath.Dir(used), filepath.Ext(used)
+	}
+
+	path := filepath.Join(dir, "config."+profile+ext)
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil
+	}
+
+	pv := viper.New()
+	pv.SetConfigFile(path)
+	if err := pv.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("error reading profile config file: %w", err)
+	}
+
+	return pv, nil
+}
+
+// resolveFileSecrets replaces every "<key>_file" setting with a non-empty
+// path by the trimmed content of that file, stored under "<key>". This lets
+// secrets be mounted as files instead of living in config.toml. The file
+// counts as coming from the source that set "<key>_file", so a "<key>" set by
+// a source of higher precedence (e.g. an APP_ env var over a profile's
+// password_file) is kept and the file is not read.
+func resolveFileSecrets(v, profile *viper.Viper) error {
+	for _, key := range v.AllKeys() {
+		if !strings.HasSuffix(key, "_file") {
+			continue
 		}
+
+		path := v.GetString(key)
+		if path == "" {
+			continue
+		}
+
+		target := strings.TrimSuffix(key, "_file")
+		if origin(v, profile, target) < origin(v, profile, key) {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %w", key, err)
+		}
+		v.Set(target, strings.TrimRight(string(content), "\r\n"))
 	}
 
-	// Unmarshal configuration into struct
-	if err := v.Unmarshal(cfg); err != nil {
-		return nil, fmt.Errorf("unable to decode config: %w", err)
+	return nil
+}
+
+// Origins of a setting, in the precedence order documented on Load
+const (
+	originFlag = iota
+	originEnv
+	originProfile
+	originFile
+	originDefault
+)
+
+// origin returns where the effective value of key comes from
+func origin(v, profile *viper.Viper, key string) int {
+	if flags != nil {
+		for name, k := range flagKeys {
+			if k == key && flags.Lookup(name).Changed {
+				return originFlag
+			}
+		}
+	}
+
+	// Viper ignores empty environment variables, and so does this check
+	if os.Getenv(EnvPrefix+"_"+strings.ToUpper(strings.ReplaceAll(key, ".", "_"))) != "" {
+		return originEnv
+	}
+
+	switch {
+	case profile != nil && profile.IsSet(key):
+		return originProfile
+	case v.InConfig(key):
+		return originFile
+	default:
+		return originDefault
+	}
+}
+
+// configFile returns the explicitly requested config file, flag first
+func configFile() string {
+	if flags != nil {
+		if f := flags.Lookup("config"); f != nil && f.Changed {
+			return f.Value.String()
+		}
 	}
 
-	return cfg, nil
+	return os.Getenv(EnvPrefix + "_CONFIG")
 }
 
-func setDefaults(v *viper.Viper) {
+// Default database passwords, refused by Validate in the production profile
+const (
+	defaultMySQLPassword    = "mysql"
+	defaultPostgresPassword = "postgresql"
+)
+
+func setDefaults(v *viper.Viper, profile string, explicit bool) {
+	// App defaults
+	v.SetDefault("app.profile", profile)
+
+	// Profile-dependent defaults: production runs gin in release mode with
+	// JSON logs and no debug endpoints, development favours readability.
+	// A deployment that forgets the profile falls back to dev, so pprof is
+	// only mounted when dev was asked for.
+	switch profile {
+	case ProfileProd, "production":
+		v.SetDefault("server.mode", "release")
+		v.SetDefault("server.debug_endpoints", false)
+		v.SetDefault("log.format", "json")
+	case ProfileTest:
+		v.SetDefault("server.mode", "test")
+		v.SetDefault("server.debug_endpoints", false)
+		v.SetDefault("log.format", "console")
+	default:
+		v.SetDefault("server.mode", "debug")
+		v.SetDefault("server.debug_endpoints", explicit)
+		v.SetDefault("log.format", "console")
+	}
+
 	// Server defaults
 	v.SetDefault("server.port", "8080")
 	v.SetDefault("server.read_timeout", "30s")
 	v.SetDefault("server.write_timeout", "30s")
+	v.SetDefault("server.trusted_proxies", []string{})
 	v.SetDefault("server.max_header_bytes", 1048576)
+	v.SetDefault("server.max_body_bytes", 1048576)
+	v.SetDefault("server.request_timeout", "10s")
+	v.SetDefault("server.routes", []map[string]any{})
+	v.SetDefault("server.error_format", "envelope")
+	v.SetDefault("server.problem_type_base", "")
+	v.SetDefault("server.shutdown_timeout", "15s")
 
 	// Log defaults
 	v.SetDefault("log.level", "info")
-	v.SetDefault("log.format", "json")
+	v.SetDefault("log.level_reset_after", "15m")
+	v.SetDefault("log.level_max_duration", "4h")
+	v.SetDefault("log.console", true)
 	v.SetDefault("log.output", "stdout")
+	v.SetDefault("log.file.path", "")
+	v.SetDefault("log.file.max_size", 100)
+	v.SetDefault("log.file.max_backups", 7)
+	v.SetDefault("log.file.max_age", 30)
+	v.SetDefault("log.file.compress", true)
+	v.SetDefault("log.redact.fields", []string{})
+	v.SetDefault("log.redact.patterns", []string{})
+	v.SetDefault("log.sampling.initial", 0)
+	v.SetDefault("log.sampling.thereafter", 100)
+	v.SetDefault("log.sampling.tick", "1s")
+	v.SetDefault("log.access.skip", []string{})
+	v.SetDefault("log.access.sample", []map[string]any{})
+	v.SetDefault("log.access.slow_threshold", "1s")
 
 	// Database defaults
 	v.SetDefault("database.mysql.host", "localhost")
 	v.SetDefault("database.mysql.port", 3306)
 	v.SetDefault("database.mysql.name", "demo")
 	v.SetDefault("database.mysql.user", "root")
-	v.SetDefault("database.mysql.password", "mysql")
+	v.SetDefault("database.mysql.password", defaultMySQLPassword)
+	v.SetDefault("database.mysql.password_file", "")
+	v.SetDefault("database.mysql.dsn", "")
+	v.SetDefault("database.mysql.tls", "false")
+	v.SetDefault("database.mysql.connect_timeout", "5s")
+	v.SetDefault("database.mysql.query_timeout", "30s")
+	v.SetDefault("database.mysql.pool.max_open_conns", 20)
+	v.SetDefault("database.mysql.pool.max_idle_conns", 10)
+	v.SetDefault("database.mysql.pool.conn_max_lifetime", "30m")
+	v.SetDefault("database.mysql.pool.conn_max_idle_time", "5m")
 
 	v.SetDefault("database.postgres.host", "localhost")
 	v.SetDefault("database.postgres.port", 5432)
 	v.SetDefault("database.postgres.name", "demo")
 	v.SetDefault("database.postgres.user", "postgres")
-	v.SetDefault("database.postgres.password", "postgresql")
+	v.SetDefault("database.postgres.password", defaultPostgresPassword)
+	v.SetDefault("database.postgres.password_file", "")
+	v.SetDefault("database.postgres.dsn", "")
+	v.SetDefault("database.postgres.sslmode", "disable")
+	v.SetDefault("database.postgres.sslrootcert", "")
+	v.SetDefault("database.postgres.connect_timeout", "5s")
+	v.SetDefault("database.postgres.query_timeout", "30s")
+	v.SetDefault("database.postgres.pool.max_open_conns", 20)
+	v.SetDefault("database.postgres.pool.max_idle_conns", 10)
+	v.SetDefault("database.postgres.pool.conn_max_lifetime", "30m")
+	v.SetDefault("database.postgres.pool.conn_max_idle_time", "5m")
+
+	v.SetDefault("database.retry.initial_interval", "500ms")
+	v.SetDefault("database.retry.max_interval", "30s")
+	v.SetDefault("database.retry.multiplier", 2.0)
+
+	// Health defaults
+	v.SetDefault("health.required", []string{"mysql"})
+	v.SetDefault("health.timeout", "2s")
+
+	// Admin endpoints are disabled until a token is configured
+	v.SetDefault("admin.token", "")
+	v.SetDefault("admin.token_file", "")
+
+	// CORS is off until origins are listed
+	v.SetDefault("cors.allow_origins", []string{})
+	v.SetDefault("cors.allow_methods", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"})
+	v.SetDefault("cors.allow_headers", []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-Request-ID"})
+	v.SetDefault("cors.expose_headers", []string{"X-Request-ID"})
+	v.SetDefault("cors.allow_credentials", false)
+	v.SetDefault("cors.max_age", "12h")
+	v.SetDefault("cors.groups", []map[string]any{})
+
+	// Authentication is opt-in; config.prod.toml enables it
+	v.SetDefault("auth.enabled", false)
+	v.SetDefault("auth.jwt.issuer", "")
+	v.SetDefault("auth.jwt.audience", "")
+	v.SetDefault("auth.jwt.leeway", "30s")
+	v.SetDefault("auth.jwt.hs256_secret", "")
+	v.SetDefault("auth.jwt.hs256_secret_file", "")
+	v.SetDefault("auth.jwt.rs256_public_key", "")
+	v.SetDefault("auth.jwt.rs256_public_key_file", "")
+	v.SetDefault("auth.jwt.jwks", "")
+	v.SetDefault("auth.jwt.jwks_file", "")
+	v.SetDefault("auth.api_keys.database", "mysql")
+	v.SetDefault("auth.users.database", "mysql")
+
+	v.SetDefault("rate_limit.enabled", true)
+	v.SetDefault("rate_limit.requests", 300)
+	v.SetDefault("rate_limit.period", "1m")
+	v.SetDefault("rate_limit.burst", 50)
+	v.SetDefault("rate_limit.routes", []map[string]any{})
+
+	// Feature toggles default to off
+	v.SetDefault("features", map[string]bool{})
+}
+
+// ParseDuration parses a duration setting such as "30s", returning fallback
+// when the value is empty or malformed.
+func ParseDuration(value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Warn("Invalid duration in config, using fallback", "value", value, "fallback", fallback)
+		return fallback
+	}
+
+	return d
 }
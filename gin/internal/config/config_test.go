@@ -0,0 +1,437 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeConfig writes a config file into a temp dir and returns its path
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// withFlags registers and parses args for the duration of the test
+func withFlags(t *testing.T, args ...string) {
+	t.Helper()
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	RegisterFlags(fs)
+	assert.NoError(t, fs.Parse(args))
+	t.Cleanup(func() { flags = nil })
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "8080", cfg.Server.Port)
+	assert.Equal(t, 3306, cfg.Database.MySQL.Port)
+	assert.Equal(t, []string{"mysql"}, cfg.Health.Required)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeConfig(t, `
+[server]
+port = "9000"
+
+[log]
+level = "warn"
+
+[database.mysql]
+host = "file-host"
+password = "from-file"
+`)
+
+	t.Run("config file from env", func(t *testing.T) {
+		t.Setenv("APP_CONFIG", path)
+
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Equal(t, "9000", cfg.Server.Port)
+		assert.Equal(t, "file-host", cfg.Database.MySQL.Host)
+		// Keys missing from the file keep their defaults
+		assert.Equal(t, 3306, cfg.Database.MySQL.Port)
+	})
+
+	t.Run("env overrides file", func(t *testing.T) {
+		t.Setenv("APP_CONFIG", path)
+		t.Setenv("APP_DATABASE_MYSQL_PASSWORD", "from-env")
+		t.Setenv("APP_HEALTH_REQUIRED", "mysql,postgres")
+
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Equal(t, "from-env", cfg.Database.MySQL.Password)
+		assert.Equal(t, []string{"mysql", "postgres"}, cfg.Health.Required)
+	})
+
+	t.Run("flags override env", func(t *testing.T) {
+		t.Setenv("APP_SERVER_PORT", "9100")
+		t.Setenv("APP_LOG_LEVEL", "error")
+		withFlags(t, "--config", path, "--port", "9200")
+
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Equal(t, "9200", cfg.Server.Port)
+		assert.Equal(t, "error", cfg.Log.Level)
+		// Unset flags do not mask lower-precedence sources
+		assert.Equal(t, "file-host", cfg.Database.MySQL.Host)
+		assert.Equal(t, 3306, cfg.Database.MySQL.Port)
+	})
+
+	t.Run("missing explicit file", func(t *testing.T) {
+		t.Setenv("APP_CONFIG", filepath.Join(t.TempDir(), "missing.toml"))
+
+		_, err := Load()
+		assert.Error(t, err)
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("defaults are valid", func(t *testing.T) {
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("reports every problem", func(t *testing.T) {
+		cfg, err := Load()
+		assert.NoError(t, err)
+
+		cfg.Server.Port = "http"
+		cfg.Server.ReadTimeout = "30"
+		cfg.Log.Level = "verbose"
+		cfg.Log.Format = "xml"
+		cfg.Database.MySQL.Host = ""
+		cfg.Database.Postgres.Port = 70000
+		cfg.Health.Required = []string{"redis"}
+		cfg.Log.Access.Sample = []RouteSample{{Route: "/healthz", Every: 0}}
+		cfg.RateLimit.Period = "0s"
+		cfg.Server.TrustedProxies = []string{"10.0.0.0/8", "proxy.local"}
+		cfg.Server.ErrorFormat = "xml"
+		cfg.Server.ProblemTypeBase = "errors.example.com"
+		cfg.Log.LevelResetAfter = "8h"
+
+		err = cfg.Validate()
+		var verr *ValidationError
+		assert.ErrorAs(t, err, &verr)
+
+		var keys []string
+		for _, fe := range verr.Errors {
+			keys = append(keys, fe.Key)
+		}
+		assert.ElementsMatch(t, []string{
+			"server.port",
+			"server.read_timeout",
+			"log.level",
+			"log.format",
+			"database.mysql.host",
+			"database.postgres.port",
+			"health.required",
+			"log.access.sample[0].every",
+			"rate_limit.period",
+			"server.trusted_proxies",
+			"server.error_format",
+			"server.problem_type_base",
+			"log.level_reset_after",
+		}, keys)
+		assert.Contains(t, err.Error(), "13 problems")
+	})
+
+	t.Run("dsn replaces connection fields", func(t *testing.T) {
+		cfg, err := Load()
+		assert.NoError(t, err)
+
+		cfg.Database.MySQL = MySQLConfig{DSN: "root:pw@tcp(db:3306)/demo"}
+		assert.NoError(t, cfg.Validate())
+	})
+}
+
+func TestReload(t *testing.T) {
+	path := writeConfig(t, `
+[server]
+port = "8080"
+
+[log]
+level = "info"
+
+[features]
+beta = false
+`)
+	t.Setenv("APP_CONFIG", path)
+
+	initial, err := Load()
+	assert.NoError(t, err)
+	current.Store(initial)
+	t.Cleanup(func() { current.Store(nil) })
+
+	var got *Config
+	unsubscribe := Subscribe(func(old, new *Config) {
+		assert.Same(t, initial, old)
+		got = new
+	})
+	defer unsubscribe()
+
+	t.Run("applies live settings and pins static ones", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(path, []byte(`
+[server]
+port = "9090"
+
+[log]
+level = "debug"
+
+[features]
+beta = true
+newCheckout = true
+`), 0o600))
+
+		reload()
+
+		assert.NotNil(t, got)
+		assert.Same(t, got, GetConfig())
+		assert.Equal(t, "debug", got.Log.Level)
+		assert.True(t, got.Feature("beta"))
+		// Viper lower-cases the key; the lookup does too
+		assert.True(t, got.Feature("newCheckout"))
+		assert.True(t, got.Feature("newcheckout"))
+		// The listen port cannot change without a restart
+		assert.Equal(t, "8080", got.Server.Port)
+		// The previous snapshot is left untouched
+		assert.Equal(t, "info", initial.Log.Level)
+	})
+
+	t.Run("rejects invalid config", func(t *testing.T) {
+		before := GetConfig()
+		got = nil
+		assert.NoError(t, os.WriteFile(path, []byte(`
+[log]
+level = "loud"
+`), 0o600))
+
+		reload()
+
+		assert.Nil(t, got)
+		assert.Same(t, before, GetConfig())
+	})
+}
+
+func TestPinStatic(t *testing.T) {
+	old, err := Load()
+	assert.NoError(t, err)
+	next, err := Load()
+	assert.NoError(t, err)
+
+	next.Server.Port = "9999"
+	next.Database.MySQL.Host = "elsewhere"
+	next.Log.Level = "debug"
+
+	keys := pinStatic(old, next)
+	assert.ElementsMatch(t, []string{"server.port", "database.mysql"}, keys)
+	assert.Equal(t, old.Server.Port, next.Server.Port)
+	assert.Equal(t, old.Database.MySQL.Host, next.Database.MySQL.Host)
+	assert.Equal(t, "debug", next.Log.Level)
+}
+
+func TestLoad_FileSecrets(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "mysql_password")
+	assert.NoError(t, os.WriteFile(secret, []byte("s3cr3t\n"), 0o600))
+
+	t.Run("password_file replaces password", func(t *testing.T) {
+		t.Setenv("APP_DATABASE_MYSQL_PASSWORD_FILE", secret)
+
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Equal(t, "s3cr3t", cfg.Database.MySQL.Password)
+	})
+
+	t.Run("unreadable file", func(t *testing.T) {
+		t.Setenv("APP_DATABASE_MYSQL_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+
+		_, err := Load()
+		assert.Error(t, err)
+	})
+
+	t.Run("env var outranks a password_file from the config file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.toml")
+		assert.NoError(t, os.WriteFile(path, []byte("[database.mysql]\npassword_file = \""+secret+"\"\n"), 0o600))
+		t.Setenv("APP_CONFIG", path)
+		t.Setenv("APP_DATABASE_MYSQL_PASSWORD", "from-env")
+
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Equal(t, "from-env", cfg.Database.MySQL.Password)
+	})
+
+	t.Run("password_file from env outranks the config file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.toml")
+		assert.NoError(t, os.WriteFile(path, []byte("[database.mysql]\npassword = \"from-file\"\n"), 0o600))
+		t.Setenv("APP_CONFIG", path)
+		t.Setenv("APP_DATABASE_MYSQL_PASSWORD_FILE", secret)
+
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Equal(t, "s3cr3t", cfg.Database.MySQL.Password)
+	})
+}
+
+func TestConfig_Validate_ProductionPasswords(t *testing.T) {
+	t.Setenv("APP_PROFILE", "prod")
+
+	cfg, err := Load()
+	assert.NoError(t, err)
+	assert.True(t, cfg.IsProduction())
+
+	err = cfg.Validate()
+	assert.ErrorContains(t, err, "database.mysql.password: default password")
+	assert.ErrorContains(t, err, "database.postgres.password: default password")
+
+	cfg.Database.MySQL.Password = "changed"
+	cfg.Database.Postgres.Password = "changed"
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	cfg, err := Load()
+	assert.NoError(t, err)
+	cfg.Database.Postgres.DSN = "postgres://u:p@h/db"
+	cfg.Admin.Token = "admin-token"
+	cfg.Database.MySQL.PasswordFile = "/run/secrets/mysql"
+
+	dump := cfg.Redacted()
+
+	mysql := dump["database"].(map[string]any)["mysql"].(map[string]any)
+	postgres := dump["database"].(map[string]any)["postgres"].(map[string]any)
+	assert.Equal(t, Redacted, mysql["password"])
+	assert.Equal(t, "/run/secrets/mysql", mysql["password_file"])
+	assert.Equal(t, "localhost", mysql["host"])
+	assert.Equal(t, Redacted, postgres["dsn"])
+	assert.Equal(t, Redacted, dump["admin"].(map[string]any)["token"])
+	assert.Equal(t, "8080", dump["server"].(map[string]any)["port"])
+
+	// The snapshot itself is left intact
+	assert.Equal(t, "admin-token", cfg.Admin.Token)
+}
+
+func TestLoad_Profiles(t *testing.T) {
+	path := writeConfig(t, `
+[server]
+port = "9000"
+
+[log]
+level = "info"
+`)
+	profilePath := filepath.Join(filepath.Dir(path), "config.prod.toml")
+	assert.NoError(t, os.WriteFile(profilePath, []byte(`
+[log]
+level = "warn"
+
+[database.mysql]
+host = "mysql.internal"
+`), 0o600))
+
+	t.Run("dev profile by default", func(t *testing.T) {
+		t.Setenv("APP_CONFIG", path)
+
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Equal(t, ProfileDev, cfg.App.Profile)
+		assert.Equal(t, "debug", cfg.Server.Mode)
+		assert.Equal(t, "console", cfg.Log.Format)
+		assert.False(t, cfg.Server.DebugEndpoints)
+		assert.Equal(t, "info", cfg.Log.Level)
+	})
+
+	t.Run("explicit dev profile enables debug endpoints", func(t *testing.T) {
+		t.Setenv("APP_CONFIG", path)
+		t.Setenv("APP_PROFILE", ProfileDev)
+
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Equal(t, ProfileDev, cfg.App.Profile)
+		assert.True(t, cfg.Server.DebugEndpoints)
+	})
+
+	t.Run("profile file layered over base", func(t *testing.T) {
+		t.Setenv("APP_CONFIG", path)
+		t.Setenv("APP_PROFILE", ProfileProd)
+
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Equal(t, ProfileProd, cfg.App.Profile)
+		assert.Equal(t, "release", cfg.Server.Mode)
+		assert.Equal(t, "json", cfg.Log.Format)
+		assert.False(t, cfg.Server.DebugEndpoints)
+		assert.Equal(t, "warn", cfg.Log.Level)
+		assert.Equal(t, "mysql.internal", cfg.Database.MySQL.Host)
+		// Keys only in the base file are kept
+		assert.Equal(t, "9000", cfg.Server.Port)
+	})
+
+	t.Run("profile flag wins over env", func(t *testing.T) {
+		t.Setenv("APP_PROFILE", ProfileProd)
+		withFlags(t, "--config", path, "--profile", ProfileTest)
+
+		cfg, err := Load()
+		assert.NoError(t, err)
+		assert.Equal(t, ProfileTest, cfg.App.Profile)
+		assert.Equal(t, "test", cfg.Server.Mode)
+		assert.Equal(t, "info", cfg.Log.Level)
+	})
+}
+
+func TestLoad_CORSGroups(t *testing.T) {
+	t.Setenv("APP_CONFIG", writeConfig(t, `
+[cors]
+allow_origins = ["https://app.example.com"]
+allow_credentials = true
+
+[[cors.groups]]
+prefix = "/admin"
+allow_origins = []
+
+[[cors.groups]]
+prefix = "/public"
+allow_origins = ["*"]
+allow_credentials = false
+`))
+
+	cfg, err := Load()
+	assert.NoError(t, err)
+	assert.NoError(t, cfg.Validate())
+	assert.Len(t, cfg.CORS.Groups, 2)
+
+	admin := cfg.CORS.Policy(cfg.CORS.Groups[0])
+	assert.NotNil(t, cfg.CORS.Groups[0].AllowOrigins)
+	assert.Empty(t, admin.AllowOrigins)
+	assert.True(t, admin.AllowCredentials)
+
+	public := cfg.CORS.Policy(cfg.CORS.Groups[1])
+	assert.Equal(t, []string{"*"}, public.AllowOrigins)
+	assert.False(t, public.AllowCredentials)
+	// Unset keys inherit the default policy
+	assert.Equal(t, cfg.CORS.AllowMethods, public.AllowMethods)
+
+	cfg.CORS.Groups[1].AllowCredentials = nil
+	cfg.CORS.AllowOrigins = []string{"app.example.com"}
+	err = cfg.Validate()
+	assert.ErrorContains(t, err, "cors.allow_origins: origin must start with http:// or https://")
+	assert.ErrorContains(t, err, `cors.groups[1].allow_origins: "*" cannot be combined with allow_credentials`)
+}
+
+// The shipped prod profile must start with secrets from env vars alone
+func TestLoad_ShippedProdProfileEnvOnly(t *testing.T) {
+	t.Setenv("APP_CONFIG", filepath.Join("..", "..", "config", "config.toml"))
+	t.Setenv("APP_PROFILE", ProfileProd)
+	t.Setenv("APP_DATABASE_MYSQL_PASSWORD", "mysql-from-env")
+	t.Setenv("APP_DATABASE_POSTGRES_PASSWORD", "postgres-from-env")
+
+	cfg, err := Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "mysql-from-env", cfg.Database.MySQL.Password)
+	assert.Equal(t, "postgres-from-env", cfg.Database.Postgres.Password)
+	assert.NoError(t, cfg.Validate())
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Redacted is the placeholder written in place of secret values
+const Redacted = "******"
+
+// sensitiveKeys are key fragments whose values are never shown in a dump
+var sensitiveKeys = []string{"password", "secret", "token", "dsn", "private_key"}
+
+// isSensitive reports whether a config key holds a secret. Paths to secret
+// files ("*_file") are not secrets themselves.
+func isSensitive(key string) bool {
+	if strings.HasSuffix(key, "_file") {
+		return false
+	}
+	for _, fragment := range sensitiveKeys {
+		if strings.Contains(key, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted returns the effective configuration as a nested map keyed like
+// config.toml, with every non-empty secret replaced by Redacted.
+func (c *Config) Redacted() map[string]any {
+	return redactStruct(reflect.ValueOf(c).Elem())
+}
+
+func redactStruct(v reflect.Value) map[string]any {
+	out := make(map[string]any, v.NumField())
+	t := v.Type()
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		key := strings.Split(field.Tag.Get("toml"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		out[key] = redactValue(key, v.Field(i))
+	}
+
+	return out
+}
+
+func redactValue(key string, v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Struct:
+		return redactStruct(v)
+	case reflect.Map:
+		m := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			k := iter.Key().String()
+			m[k] = redactValue(k, iter.Value())
+		}
+		return m
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		items := make([]any, v.Len())
+		for i := range v.Len() {
+			items[i] = redactValue(key, v.Index(i))
+		}
+		return items
+	case reflect.String:
+		if v.String() != "" && isSensitive(key) {
+			return Redacted
+		}
+	}
+
+	return v.Interface()
+}
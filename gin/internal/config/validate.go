@@ -0,0 +1,300 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError describes a single invalid setting
+type FieldError struct {
+	Key     string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Key + ": " + e.Message
+}
+
+// ValidationError aggregates every problem found by Config.Validate
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration (%d problems):", len(e.Errors))
+	for _, fe := range e.Errors {
+		b.WriteString("\n  - ")
+		b.WriteString(fe.Error())
+	}
+	return b.String()
+}
+
+var (
+	logLevels    = []string{"debug", "info", "warn", "error"}
+	logFormats   = []string{"json", "console"}
+	logOutputs   = []string{"stdout", "stderr"}
+	ginModes     = []string{"debug", "release", "test"}
+	errorFormats = []string{"envelope", "problem"}
+	backends     = []string{"mysql", "postgres"}
+	sslModes     = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	mysqlTLSOpts = []string{"", "true", "false", "skip-verify", "preferred"}
+)
+
+// minHS256SecretLength matches the HMAC-SHA256 output size
+const minHS256SecretLength = 32
+
+// validator collects field errors so that all of them can be reported at once
+type validator struct {
+	errs []FieldError
+}
+
+func (v *validator) addf(key, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) port(key, value string) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		v.addf(key, "must be numeric, got %q", value)
+		return
+	}
+	v.portNumber(key, n)
+}
+
+func (v *validator) portNumber(key string, n int) {
+	if n < 1 || n > 65535 {
+		v.addf(key, "must be between 1 and 65535, got %d", n)
+	}
+}
+
+// duration checks value parses; empty values are allowed when optional
+func (v *validator) duration(key, value string, optional bool) {
+	if value == "" {
+		if !optional {
+			v.addf(key, "is required")
+		}
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		v.addf(key, "must be a duration such as \"30s\", got %q", value)
+		return
+	}
+	if d < 0 {
+		v.addf(key, "must not be negative, got %s", value)
+	}
+}
+
+func (v *validator) oneOf(key, value string, allowed []string) {
+	if !slices.Contains(allowed, value) {
+		v.addf(key, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+	}
+}
+
+func (v *validator) required(key, value string) {
+	if value == "" {
+		v.addf(key, "is required")
+	}
+}
+
+func (v *validator) pool(prefix string, p PoolConfig) {
+	if p.MaxOpenConns < 0 {
+		v.addf(prefix+".max_open_conns", "must not be negative")
+	}
+	if p.MaxIdleConns < 0 {
+		v.addf(prefix+".max_idle_conns", "must not be negative")
+	}
+	v.duration(prefix+".conn_max_lifetime", p.ConnMaxLifetime, true)
+	v.duration(prefix+".conn_max_idle_time", p.ConnMaxIdleTime, true)
+}
+
+// cors checks one effective CORS policy
+func (v *validator) cors(prefix string, p CORSConfig) {
+	for _, origin := range p.AllowOrigins {
+		if origin == "*" {
+			if p.AllowCredentials {
+				v.addf(prefix+".allow_origins", "\"*\" cannot be combined with allow_credentials; list the origins instead")
+			}
+			continue
+		}
+		if !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			v.addf(prefix+".allow_origins", "origin must start with http:// or https://, got %q", origin)
+		}
+	}
+	v.duration(prefix+".max_age", p.MaxAge, true)
+}
+
+// limit checks a token bucket definition
+func (v *validator) limit(prefix string, requests int, period string, burst int) {
+	if requests < 1 {
+		v.addf(prefix+".requests", "must be at least 1, got %d", requests)
+	}
+	if d, err := time.ParseDuration(period); err != nil || d <= 0 {
+		v.addf(prefix+".period", "must be a positive duration such as \"1m\", got %q", period)
+	}
+	if burst < 0 {
+		v.addf(prefix+".burst", "must not be negative")
+	}
+}
+
+// Validate checks the whole configuration and returns a *ValidationError
+// listing every problem, or nil if the configuration is usable.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	// Server
+	v.port("server.port", c.Server.Port)
+	v.duration("server.read_timeout", c.Server.ReadTimeout, true)
+	v.duration("server.write_timeout", c.Server.WriteTimeout, true)
+	v.duration("server.shutdown_timeout", c.Server.ShutdownTimeout, true)
+	if c.Server.MaxHeaderBytes < 0 {
+		v.addf("server.max_header_bytes", "must not be negative")
+	}
+	v.oneOf("server.mode", c.Server.Mode, ginModes)
+	if c.Server.MaxBodyBytes < 0 {
+		v.addf("server.max_body_bytes", "must not be negative")
+	}
+	v.duration("server.request_timeout", c.Server.RequestTimeout, true)
+	for i, r := range c.Server.Routes {
+		key := fmt.Sprintf("server.routes[%d]", i)
+		v.required(key+".method", r.Method)
+		v.required(key+".route", r.Route)
+		if r.MaxBodyBytes < 0 {
+			v.addf(key+".max_body_bytes", "must not be negative")
+		}
+		v.duration(key+".request_timeout", r.RequestTimeout, true)
+	}
+	v.oneOf("server.error_format", c.Server.ErrorFormat, errorFormats)
+	if base := c.Server.ProblemTypeBase; base != "" && !strings.HasPrefix(base, "http://") && !strings.HasPrefix(base, "https://") {
+		v.addf("server.problem_type_base", "must start with http:// or https://, got %q", base)
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, err := netip.ParsePrefix(proxy); err != nil {
+			if _, err := netip.ParseAddr(proxy); err != nil {
+				v.addf("server.trusted_proxies", "must be IP addresses or CIDR ranges, got %q", proxy)
+			}
+		}
+	}
+
+	// Log
+	v.oneOf("log.level", c.Log.Level, logLevels)
+	v.duration("log.level_reset_after", c.Log.LevelResetAfter, true)
+	v.duration("log.level_max_duration", c.Log.LevelMaxDuration, true)
+	if c.Log.LevelMaxDuration != "" && ParseDuration(c.Log.LevelResetAfter, 0) > ParseDuration(c.Log.LevelMaxDuration, 0) {
+		v.addf("log.level_reset_after", "must not exceed log.level_max_duration (%s), got %s", c.Log.LevelMaxDuration, c.Log.LevelResetAfter)
+	}
+	v.oneOf("log.format", c.Log.Format, logFormats)
+	v.oneOf("log.output", c.Log.Output, logOutputs)
+	if !c.Log.Console && c.Log.File.Path == "" {
+		v.addf("log.console", "must be enabled when log.file.path is empty, otherwise nothing is logged")
+	}
+	if c.Log.File.MaxSize < 0 || c.Log.File.MaxBackups < 0 || c.Log.File.MaxAge < 0 {
+		v.addf("log.file", "max_size, max_backups and max_age must not be negative")
+	}
+	if c.Log.Sampling.Initial < 0 || c.Log.Sampling.Thereafter < 0 {
+		v.addf("log.sampling", "initial and thereafter must not be negative")
+	}
+	v.duration("log.sampling.tick", c.Log.Sampling.Tick, c.Log.Sampling.Initial == 0)
+	for i, s := range c.Log.Access.Sample {
+		key := fmt.Sprintf("log.access.sample[%d]", i)
+		v.required(key+".route", s.Route)
+		if s.Every < 1 {
+			v.addf(key+".every", "must be at least 1, got %d", s.Every)
+		}
+	}
+	v.duration("log.access.slow_threshold", c.Log.Access.SlowThreshold, true)
+	for _, p := range c.Log.Redact.Patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			v.addf("log.redact.patterns", "invalid regexp %q: %v", p, err)
+		}
+	}
+
+	// Database
+	mysql := c.Database.MySQL
+	if mysql.DSN == "" {
+		v.required("database.mysql.host", mysql.Host)
+		v.portNumber("database.mysql.port", mysql.Port)
+		v.required("database.mysql.name", mysql.Name)
+		v.required("database.mysql.user", mysql.User)
+		v.oneOf("database.mysql.tls", mysql.TLS, mysqlTLSOpts)
+	}
+	v.duration("database.mysql.connect_timeout", mysql.ConnectTimeout, true)
+	v.duration("database.mysql.query_timeout", mysql.QueryTimeout, true)
+	v.pool("database.mysql.pool", mysql.Pool)
+
+	postgres := c.Database.Postgres
+	if postgres.DSN == "" {
+		v.required("database.postgres.host", postgres.Host)
+		v.portNumber("database.postgres.port", postgres.Port)
+		v.required("database.postgres.name", postgres.Name)
+		v.required("database.postgres.user", postgres.User)
+		v.oneOf("database.postgres.sslmode", postgres.SSLMode, sslModes)
+	}
+	v.duration("database.postgres.connect_timeout", postgres.ConnectTimeout, true)
+	v.duration("database.postgres.query_timeout", postgres.QueryTimeout, true)
+	v.pool("database.postgres.pool", postgres.Pool)
+
+	// Never run production on the passwords shipped in setDefaults
+	if c.IsProduction() {
+		if mysql.DSN == "" && mysql.Password == defaultMySQLPassword {
+			v.addf("database.mysql.password", "default password is not allowed in the %q profile; set password_file or %s_DATABASE_MYSQL_PASSWORD", c.App.Profile, EnvPrefix)
+		}
+		if postgres.DSN == "" && postgres.Password == defaultPostgresPassword {
+			v.addf("database.postgres.password", "default password is not allowed in the %q profile; set password_file or %s_DATABASE_POSTGRES_PASSWORD", c.App.Profile, EnvPrefix)
+		}
+	}
+
+	v.duration("database.retry.initial_interval", c.Database.Retry.InitialInterval, true)
+	v.duration("database.retry.max_interval", c.Database.Retry.MaxInterval, true)
+	if c.Database.Retry.Multiplier != 0 && c.Database.Retry.Multiplier < 1 {
+		v.addf("database.retry.multiplier", "must be at least 1, got %g", c.Database.Retry.Multiplier)
+	}
+
+	// Health
+	for _, name := range c.Health.Required {
+		v.oneOf("health.required", name, backends)
+	}
+	v.duration("health.timeout", c.Health.Timeout, true)
+
+	// CORS
+	v.cors("cors", c.CORS)
+	for i, g := range c.CORS.Groups {
+		key := fmt.Sprintf("cors.groups[%d]", i)
+		if !strings.HasPrefix(g.Prefix, "/") {
+			v.addf(key+".prefix", "must start with \"/\", got %q", g.Prefix)
+		}
+		v.cors(key, c.CORS.Policy(g))
+	}
+
+	// Auth
+	jwt := c.Auth.JWT
+	v.duration("auth.jwt.leeway", jwt.Leeway, true)
+	v.oneOf("auth.api_keys.database", c.Auth.APIKeys.Database, backends)
+	v.oneOf("auth.users.database", c.Auth.Users.Database, backends)
+	if jwt.HS256Secret != "" && len(jwt.HS256Secret) < minHS256SecretLength {
+		v.addf("auth.jwt.hs256_secret", "must be at least %d bytes long", minHS256SecretLength)
+	}
+
+	// Rate limiting
+	if c.RateLimit.Enabled {
+		v.limit("rate_limit", c.RateLimit.Requests, c.RateLimit.Period, c.RateLimit.Burst)
+		for i, r := range c.RateLimit.Routes {
+			key := fmt.Sprintf("rate_limit.routes[%d]", i)
+			v.required(key+".method", r.Method)
+			v.required(key+".route", r.Route)
+			v.limit(key, r.Requests, r.Period, r.Burst)
+		}
+	}
+
+	if len(v.errs) > 0 {
+		return &ValidationError{Errors: v.errs}
+	}
+
+	return nil
+}
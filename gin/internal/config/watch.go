@@ -0,0 +1,140 @@
+package config
+
+import (
+	"log/slog"
+	"reflect"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// ChangeFunc is notified after a reload with the previous and the new snapshot
+type ChangeFunc func(old, new *Config)
+
+var (
+	subMu       sync.Mutex
+	subscribers = map[int]ChangeFunc{}
+	nextSubID   int
+	reloadMu    sync.Mutex
+)
+
+// staticSettings cannot be applied without a restart; a reload keeps their
+// running values and reports them as "restart required".
+var staticSettings = []struct {
+	key   string
+	field func(c *Config) any // returns a pointer to the setting
+}{
+	{"server.port", func(c *Config) any { return &c.Server.Port }},
+	{"server.read_timeout", func(c *Config) any { return &c.Server.ReadTimeout }},
+	{"server.write_timeout", func(c *Config) any { return &c.Server.WriteTimeout }},
+	{"server.max_header_bytes", func(c *Config) any { return &c.Server.MaxHeaderBytes }},
+	{"server.shutdown_timeout", func(c *Config) any { return &c.Server.ShutdownTimeout }},
+	{"server.mode", func(c *Config) any { return &c.Server.Mode }},
+	{"server.debug_endpoints", func(c *Config) any { return &c.Server.DebugEndpoints }},
+	{"server.trusted_proxies", func(c *Config) any { return &c.Server.TrustedProxies }},
+	{"database.mysql", func(c *Config) any { return &c.Database.MySQL }},
+	{"database.postgres", func(c *Config) any { return &c.Database.Postgres }},
+}
+
+// Subscribe registers fn to be called after every successful reload and
+// returns a function that removes the subscription.
+func Subscribe(fn ChangeFunc) (unsubscribe func()) {
+	subMu.Lock()
+	defer subMu.Unlock()
+
+	id := nextSubID
+	nextSubID++
+	subscribers[id] = fn
+
+	return func() {
+		subMu.Lock()
+		defer subMu.Unlock()
+		delete(subscribers, id)
+	}
+}
+
+// Watch starts watching the base and profile config files used by Init and
+// reloads the configuration whenever one changes. Files that did not exist at
+// startup are not watched.
+func Watch() {
+	if _, err := Init(); err != nil {
+		return
+	}
+
+	for _, v := range []*viper.Viper{source.base, source.profile} {
+		if v == nil || v.ConfigFileUsed() == "" {
+			continue
+		}
+
+		v.OnConfigChange(func(e fsnotify.Event) {
+			slog.Info("Config file changed, reloading", "file", e.Name)
+			reload()
+		})
+		v.WatchConfig()
+	}
+}
+
+// reload re-reads every source, publishes the new snapshot and notifies
+// subscribers. Invalid configurations are rejected and the old one is kept.
+func reload() {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	next, err := Load()
+	if err == nil {
+		err = next.Validate()
+	}
+	if err != nil {
+		slog.Error("Config reload rejected, keeping the running configuration", "error", err)
+		return
+	}
+
+	old := current.Load()
+	if old != nil {
+		if keys := pinStatic(old, next); len(keys) > 0 {
+			slog.Warn("Config changes require a restart to take effect", "keys", keys)
+		}
+	}
+
+	current.Store(next)
+	notify(old, next)
+}
+
+// Set publishes cfg as the current snapshot and notifies subscribers as a
+// reload would. Tests use it to run code against a specific configuration.
+func Set(cfg *Config) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	old := current.Swap(cfg)
+	notify(old, cfg)
+}
+
+func notify(old, next *Config) {
+	subMu.Lock()
+	fns := make([]ChangeFunc, 0, len(subscribers))
+	for _, fn := range subscribers {
+		fns = append(fns, fn)
+	}
+	subMu.Unlock()
+
+	for _, fn := range fns {
+		fn(old, next)
+	}
+}
+
+// pinStatic copies the static settings of old into next and returns the keys
+// whose values differed.
+func pinStatic(old, next *Config) []string {
+	var changed []string
+	for _, s := range staticSettings {
+		o := reflect.ValueOf(s.field(old)).Elem()
+		n := reflect.ValueOf(s.field(next)).Elem()
+		if !reflect.DeepEqual(o.Interface(), n.Interface()) {
+			changed = append(changed, s.key)
+			n.Set(o)
+		}
+	}
+	return changed
+}
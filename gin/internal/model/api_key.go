@@ -0,0 +1,40 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// APIKey is a credential for service-to-service calls. Only the salted hash
+// of the secret is stored; Prefix is public and used to look the key up.
+type APIKey struct {
+	bun.BaseModel `bun:"table:api_keys"`
+
+	ID     int64  `bun:",pk,autoincrement" json:"id"`
+	Name   string `bun:"name,notnull" json:"name"`
+	Prefix string `bun:"prefix,unique,notnull" json:"prefix"`
+	Salt   string `bun:"salt,notnull" json:"-"`
+	Hash   string `bun:"hash,notnull" json:"-"`
+	// Scopes is a space separated list, stored as text for MySQL and Postgres alike
+	Scopes     string    `bun:"scopes,notnull" json:"-"`
+	ExpiresAt  time.Time `bun:",nullzero" json:"expires_at,omitzero"`
+	LastUsedAt time.Time `bun:",nullzero" json:"last_used_at,omitzero"`
+	RevokedAt  time.Time `bun:",nullzero" json:"revoked_at,omitzero"`
+	CreatedAt  time.Time `bun:",nullzero,notnull,default:current_timestamp" json:"created_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// ScopeList returns the key's scopes
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// Active reports whether the key is neither revoked nor expired at now
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt.IsZero() && (k.ExpiresAt.IsZero() || now.Before(k.ExpiresAt))
+}
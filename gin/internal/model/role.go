@@ -0,0 +1,28 @@
+package model
+
+import "fmt"
+
+// Role grants access to user management endpoints; see the Rules of each handler
+type Role string
+
+const (
+	RoleAdmin    Role = "admin"
+	RoleOperator Role = "operator"
+	RoleViewer   Role = "viewer"
+)
+
+// Roles lists every valid role
+var Roles = []Role{RoleAdmin, RoleOperator, RoleViewer}
+
+// ParseRole returns the role named s; an empty s is the viewer role
+func ParseRole(s string) (Role, error) {
+	if s == "" {
+		return RoleViewer, nil
+	}
+	for _, r := range Roles {
+		if string(r) == s {
+			return r, nil
+		}
+	}
+	return "", fmt.Errorf("未知角色: %q", s)
+}
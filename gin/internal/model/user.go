@@ -12,6 +12,7 @@ type User struct {
 	ID        int64     `bun:",pk,autoincrement" json:"id"`
 	Name      string    `bun:"name,notnull" json:"name"`
 	Email     string    `bun:"email,unique,notnull" json:"email"`
+	Role      Role      `bun:"role,notnull,default:'viewer'" json:"role"`
 	CreatedAt time.Time `bun:",nullzero,notnull,default:current_timestamp" json:"created_at"`
 	UpdatedAt time.Time `bun:",nullzero,notnull,default:current_timestamp" json:"updated_at"`
 }
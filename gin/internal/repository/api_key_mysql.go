@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yizhinailong/demo/gin/internal/model"
+)
+
+type apiKeyMySQLRepo struct {
+	conn *Connection
+}
+
+// NewAPIKeyMySQLRepository creates a new MySQL API key repository
+func NewAPIKeyMySQLRepository() APIKeyRepository {
+	return &apiKeyMySQLRepo{conn: MySQL()}
+}
+
+func (r *apiKeyMySQLRepo) Create(ctx context.Context, key *model.APIKey) error {
+	db, err := r.conn.DB(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.NewInsert().Model(key).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("创建 API key 失败: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r *apiKeyMySQLRepo) GetByPrefix(ctx context.Context, prefix string) (*model.APIKey, error) {
+	db, err := r.conn.DB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var key model.APIKey
+	err = db.NewSelect().Model(&key).Where("prefix = ?", prefix).Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("查询 API key 失败: %w", translateError(err))
+	}
+	return &key, nil
+}
+
+func (r *apiKeyMySQLRepo) List(ctx context.Context) ([]*model.APIKey, error) {
+	db, err := r.conn.DB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*model.APIKey
+	err = db.NewSelect().Model(&keys).Order("id").Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("查询 API key 列表失败: %w", translateError(err))
+	}
+	return keys, nil
+}
+
+func (r *apiKeyMySQLRepo) Revoke(ctx context.Context, id int64, at time.Time) error {
+	db, err := r.conn.DB(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := db.NewUpdate().Model((*model.APIKey)(nil)).
+		Set("revoked_at = ?", at).
+		Where("id = ?", id).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("吊销 API key 失败: %w", translateError(err))
+	}
+	if err := requireAffected(res); err != nil {
+		return fmt.Errorf("吊销 API key 失败: %w", err)
+	}
+	return nil
+}
+
+func (r *apiKeyMySQLRepo) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	db, err := r.conn.DB(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.NewUpdate().Model((*model.APIKey)(nil)).
+		Set("last_used_at = ?", at).
+		Where("id = ?", id).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("更新 API key 使用时间失败: %w", translateError(err))
+	}
+	return nil
+}
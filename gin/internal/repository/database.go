@@ -1,91 +1,364 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/dialect/mysqldialect"
 	"github.com/uptrace/bun/dialect/pgdialect"
 
 	"github.com/yizhinailong/demo/gin/internal/config"
+	"github.com/yizhinailong/demo/gin/pkg/logger"
 
-	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
 )
 
+// ErrNotConnected is returned while a database has not been reached yet
+var ErrNotConnected = errors.New("database connection not available")
+
+// log is named so its level can be raised on its own via /admin/log/level
+var log = logger.Slog("repository")
+
+// pingTimeout bounds a single connection attempt
+const pingTimeout = 5 * time.Second
+
+// Connection lazily opens a database handle and keeps retrying with
+// exponential backoff until the database becomes reachable. Once connected,
+// database/sql takes care of re-dialing broken connections itself.
+type Connection struct {
+	name string
+	open func(ctx context.Context) (*bun.DB, error)
+
+	// mu only guards the fields below; it is never held while dialing
+	mu          sync.Mutex
+	db          *bun.DB
+	connecting  bool
+	lastErr     error
+	attempts    int
+	nextAttempt time.Time
+}
+
 var (
-	mysqlDB      *bun.DB
-	postgresDB   *bun.DB
-	mysqlOnce    sync.Once
-	postgresOnce sync.Once
+	mysqlConn    = &Connection{name: "mysql", open: openMySQL}
+	postgresConn = &Connection{name: "postgres", open: openPostgres}
 )
 
-// GetDB returns the shared database instance, initializing it if necessary
+// MySQL returns the shared MySQL connection
+func MySQL() *Connection {
+	return mysqlConn
+}
+
+// Postgres returns the shared PostgreSQL connection
+func Postgres() *Connection {
+	return postgresConn
+}
+
+// GetMySQLDB returns the shared MySQL instance, or nil if it is not reachable yet
 func GetMySQLDB() *bun.DB {
-	mysqlOnce.Do(func() {
-		cfg := config.GetConfig()
-
-		// Initialize database connection
-		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true",
-			cfg.Database.MySQL.User,
-			cfg.Database.MySQL.Password,
-			cfg.Database.MySQL.Host,
-			cfg.Database.MySQL.Port,
-			cfg.Database.MySQL.Name,
-		)
-
-		sqldb, err := sql.Open("mysql", dsn)
-		if err != nil {
-			slog.Error("Failed to connect to database", "error", err)
+	db, _ := mysqlConn.DB(context.Background())
+	return db
+}
+
+// GetPostgresDB returns the shared PostgreSQL instance, or nil if it is not reachable yet
+func GetPostgresDB() *bun.DB {
+	db, _ := postgresConn.DB(context.Background())
+	return db
+}
+
+// DB returns the database handle, attempting to connect within ctx if the
+// backoff window since the last failure has elapsed. While another caller is
+// connecting it fails fast with ErrNotConnected instead of waiting.
+func (c *Connection) DB(ctx context.Context) (*bun.DB, error) {
+	c.mu.Lock()
+	if c.db != nil {
+		db := c.db
+		c.mu.Unlock()
+		return db, nil
+	}
+	if c.connecting {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("%w for %s: connection attempt in progress", ErrNotConnected, c.name)
+	}
+	if time.Now().Before(c.nextAttempt) {
+		err := c.lastErr
+		c.mu.Unlock()
+		return nil, fmt.Errorf("%w for %s: %v", ErrNotConnected, c.name, err)
+	}
+	c.connecting = true
+	c.mu.Unlock()
+
+	db, err := c.connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w for %s: %v", ErrNotConnected, c.name, err)
+	}
+	return db, nil
+}
+
+// connect makes one attempt; the caller must have set c.connecting
+func (c *Connection) connect(ctx context.Context) (*bun.DB, error) {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	db, err := c.open(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connecting = false
+
+	if err != nil {
+		c.attempts++
+		c.lastErr = err
+		delay := backoffDelay(config.GetConfig().Database.Retry, c.attempts)
+		c.nextAttempt = time.Now().Add(delay)
+
+		log.Error("Failed to connect to database",
+			"database", c.name, "attempt", c.attempts, "retry_in", delay, "error", err)
+		return nil, err
+	}
+
+	c.db = db
+	c.lastErr = nil
+	c.attempts = 0
+	c.nextAttempt = time.Time{}
+
+	log.Info("Database connection initialized successfully", "database", c.name)
+	return db, nil
+}
+
+// Run retries the connection in the background until it succeeds or ctx is done
+func (c *Connection) Run(ctx context.Context) {
+	for {
+		c.mu.Lock()
+		if c.db != nil {
+			c.mu.Unlock()
 			return
 		}
+		wait := time.Until(c.nextAttempt)
+		if wait <= 0 && !c.connecting {
+			c.connecting = true
+			c.mu.Unlock()
+			_, _ = c.connect(ctx)
+			continue
+		}
+		c.mu.Unlock()
 
-		// Test database connection
-		if err := sqldb.Ping(); err != nil {
-			slog.Error("Failed to ping database", "error", err)
+		// A request is dialing; look again once its attempt had time to end
+		if wait <= 0 {
+			wait = pingTimeout
+		}
+
+		select {
+		case <-ctx.Done():
 			return
+		case <-time.After(wait):
 		}
+	}
+}
 
-		// Create Bun DB instance
-		mysqlDB = bun.NewDB(sqldb, mysqldialect.New())
+// Close closes the handle if it was opened; a later DB call reconnects
+func (c *Connection) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-		slog.Info("Database connection initialized successfully")
-	})
+	if c.db == nil {
+		return nil
+	}
 
-	return mysqlDB
+	err := c.db.Close()
+	c.db = nil
+	return err
 }
-func GetPostgresDB() *bun.DB {
-	postgresOnce.Do(func() {
-		cfg := config.GetConfig()
-		// Initialize PostgreSQL database connection
-		dsn := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable",
-			cfg.Database.Postgres.User,
-			cfg.Database.Postgres.Password,
-			cfg.Database.Postgres.Host,
-			cfg.Database.Postgres.Port,
-			cfg.Database.Postgres.Name,
-		)
-
-		sqldb, err := sql.Open("postgres", dsn)
-		if err != nil {
-			slog.Error("Failed to connect to PostgreSQL database", "error", err)
-			return
-		}
 
-		// Test database connection
-		if err := sqldb.Ping(); err != nil {
-			slog.Error("Failed to ping PostgreSQL database", "error", err)
-			return
+// backoffDelay returns the wait before the given (1-based) retry attempt
+func backoffDelay(cfg config.RetryConfig, attempt int) time.Duration {
+	initial := config.ParseDuration(cfg.InitialInterval, 500*time.Millisecond)
+	maxDelay := config.ParseDuration(cfg.MaxInterval, 30*time.Second)
+	multiplier := cfg.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	delay := float64(initial)
+	for i := 1; i < attempt && delay < float64(maxDelay); i++ {
+		delay *= multiplier
+	}
+
+	return min(time.Duration(delay), maxDelay)
+}
+
+// StartReconnect launches the background reconnect loops for all databases
+func StartReconnect(ctx context.Context) {
+	go mysqlConn.Run(ctx)
+	go postgresConn.Run(ctx)
+}
+
+// Close closes every database handle that has been opened so far.
+func Close() error {
+	var errs []error
+
+	if err := mysqlConn.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("close mysql: %w", err))
+	}
+
+	if err := postgresConn.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("close postgres: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+func openMySQL(ctx context.Context) (*bun.DB, error) {
+	return dialMySQL(ctx, config.GetConfig().Database.MySQL)
+}
+
+func dialMySQL(ctx context.Context, cfg config.MySQLConfig) (*bun.DB, error) {
+	dsn, err := mysqlDSN(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	sqldb, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+	applyPool(sqldb, cfg.Pool)
+
+	// Test database connection
+	if err := sqldb.PingContext(ctx); err != nil {
+		_ = sqldb.Close()
+		return nil, err
+	}
+
+	// Create Bun DB instance
+	db := bun.NewDB(sqldb, mysqldialect.New())
+	db.AddQueryHook(queryHook{database: "mysql"})
+
+	return db, nil
+}
+
+func openPostgres(ctx context.Context) (*bun.DB, error) {
+	return dialPostgres(ctx, config.GetConfig().Database.Postgres)
+}
+
+func dialPostgres(ctx context.Context, cfg config.PostgresConfig) (*bun.DB, error) {
+	sqldb, err := sql.Open("postgres", postgresDSN(cfg))
+	if err != nil {
+		return nil, err
+	}
+	applyPool(sqldb, cfg.Pool)
+
+	// Test database connection
+	if err := sqldb.PingContext(ctx); err != nil {
+		_ = sqldb.Close()
+		return nil, err
+	}
+
+	// Create Bun DB instance with PostgreSQL dialect
+	db := bun.NewDB(sqldb, pgdialect.New())
+	db.AddQueryHook(queryHook{database: "postgres"})
+
+	return db, nil
+}
+
+// mysqlDSN builds the go-sql-driver DSN, validating the result
+func mysqlDSN(cfg config.MySQLConfig) (string, error) {
+	if cfg.DSN != "" {
+		return cfg.DSN, nil
+	}
+
+	mc := mysql.NewConfig()
+	mc.User = cfg.User
+	mc.Passwd = cfg.Password
+	mc.Net = "tcp"
+	mc.Addr = net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	mc.DBName = cfg.Name
+	mc.ParseTime = true
+	mc.TLSConfig = cfg.TLS
+	mc.Timeout = config.ParseDuration(cfg.ConnectTimeout, 0)
+	mc.ReadTimeout = config.ParseDuration(cfg.QueryTimeout, 0)
+	mc.WriteTimeout = mc.ReadTimeout
+
+	dsn := mc.FormatDSN()
+	if len(cfg.Params) > 0 {
+		params := url.Values{}
+		for k, v := range cfg.Params {
+			params.Set(k, v)
+		}
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
 		}
+		dsn += sep + params.Encode()
+	}
 
-		// Create Bun DB instance with PostgreSQL dialect
-		postgresDB = bun.NewDB(sqldb, pgdialect.New())
+	// Let the driver reject unknown TLS names or malformed params up front
+	if _, err := mysql.ParseDSN(dsn); err != nil {
+		return "", fmt.Errorf("invalid mysql dsn: %w", err)
+	}
 
-		slog.Info("PostgreSQL database connection initialized successfully")
-	})
+	return dsn, nil
+}
+
+// postgresDSN builds a lib/pq connection URL
+func postgresDSN(cfg config.PostgresConfig) string {
+	if cfg.DSN != "" {
+		return cfg.DSN
+	}
+
+	query := url.Values{}
+	for k, v := range cfg.Params {
+		query.Set(k, v)
+	}
+
+	sslmode := cfg.SSLMode
+	if sslmode == "" {
+		sslmode = "disable"
+	}
+	query.Set("sslmode", sslmode)
+
+	if cfg.SSLRootCert != "" {
+		query.Set("sslrootcert", cfg.SSLRootCert)
+	}
+	if d := config.ParseDuration(cfg.ConnectTimeout, 0); d > 0 {
+		query.Set("connect_timeout", strconv.Itoa(int(max(d.Round(time.Second), time.Second)/time.Second)))
+	}
+	if d := config.ParseDuration(cfg.QueryTimeout, 0); d > 0 {
+		query.Set("statement_timeout", strconv.FormatInt(d.Milliseconds(), 10))
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		Path:     "/" + cfg.Name,
+		RawQuery: query.Encode(),
+	}
+
+	return u.String()
+}
 
-	return postgresDB
+// applyPool applies the pool settings; zero values keep the database/sql defaults
+func applyPool(sqldb *sql.DB, cfg config.PoolConfig) {
+	if cfg.MaxOpenConns > 0 {
+		sqldb.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqldb.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if d := config.ParseDuration(cfg.ConnMaxLifetime, 0); d > 0 {
+		sqldb.SetConnMaxLifetime(d)
+	}
+	if d := config.ParseDuration(cfg.ConnMaxIdleTime, 0); d > 0 {
+		sqldb.SetConnMaxIdleTime(d)
+	}
 }
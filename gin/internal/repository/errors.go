@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when no row matches; it also matches sql.ErrNoRows
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicate is returned when a unique constraint rejects a write
+	ErrDuplicate = errors.New("duplicate record")
+)
+
+const (
+	mysqlDuplicateEntry     = 1062
+	postgresUniqueViolation = "23505"
+)
+
+// translateError tags driver errors with ErrNotFound or ErrDuplicate while
+// keeping the original error in the chain
+func translateError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return fmt.Errorf("%w: %w", ErrDuplicate, err)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == postgresUniqueViolation {
+		return fmt.Errorf("%w: %w", ErrDuplicate, err)
+	}
+
+	return err
+}
+
+// requireAffected turns an update or delete that touched no row into ErrNotFound
+func requireAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %w", ErrNotFound, sql.ErrNoRows)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslateError(t *testing.T) {
+	t.Run("no rows", func(t *testing.T) {
+		err := translateError(fmt.Errorf("scan: %w", sql.ErrNoRows))
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("mysql duplicate entry", func(t *testing.T) {
+		driverErr := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@example.com' for key 'users.email'"}
+		err := translateError(driverErr)
+		assert.ErrorIs(t, err, ErrDuplicate)
+		assert.ErrorIs(t, err, driverErr)
+	})
+
+	t.Run("postgres unique violation", func(t *testing.T) {
+		err := translateError(&pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint"})
+		assert.ErrorIs(t, err, ErrDuplicate)
+	})
+
+	t.Run("other driver errors pass through", func(t *testing.T) {
+		for _, driverErr := range []error{
+			&mysql.MySQLError{Number: 1146, Message: "Table doesn't exist"},
+			&pq.Error{Code: "42P01", Message: "relation does not exist"},
+			errors.New("connection refused"),
+		} {
+			assert.Same(t, driverErr, translateError(driverErr))
+		}
+	})
+}
+
+type affected int64
+
+func (a affected) LastInsertId() (int64, error) { return 0, nil }
+func (a affected) RowsAffected() (int64, error) { return int64(a), nil }
+
+func TestRequireAffected(t *testing.T) {
+	assert.NoError(t, requireAffected(affected(1)))
+	assert.ErrorIs(t, requireAffected(affected(0)), ErrNotFound)
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+	"go.uber.org/zap"
+
+	"github.com/yizhinailong/demo/gin/pkg/logger"
+)
+
+// queryHook logs every bun query with the request ID carried by ctx, so a
+// query can be traced back to the HTTP request that issued it. Queries are
+// logged at debug level; raise "repository.query" via /admin/log/level to see
+// them.
+type queryHook struct {
+	database string
+}
+
+var _ bun.QueryHook = queryHook{}
+
+func (h queryHook) BeforeQuery(ctx context.Context, _ *bun.QueryEvent) context.Context {
+	return ctx
+}
+
+func (h queryHook) AfterQuery(ctx context.Context, event *bun.QueryEvent) {
+	log := logger.FromContext(ctx).Named("repository.query")
+	fields := []zap.Field{
+		zap.String("database", h.database),
+		zap.String("operation", event.Operation()),
+		zap.String("query", event.Query),
+		zap.Duration("duration", time.Since(event.StartTime)),
+	}
+
+	if event.Err != nil && !errors.Is(event.Err, sql.ErrNoRows) {
+		log.Warn("Query failed", append(fields, zap.Error(event.Err))...)
+		return
+	}
+	log.Debug("Query executed", fields...)
+}
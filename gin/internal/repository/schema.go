@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
+
+	"github.com/yizhinailong/demo/gin/internal/config"
+	"github.com/yizhinailong/demo/gin/internal/model"
+)
+
+// Migrate brings the schema of the named database ("mysql" or "postgres") up
+// to date. It is run by cmd/migrate ahead of a deployment and never by the
+// API server: building an index on a large users table takes far longer than
+// a connection attempt may, and on PostgreSQL blocks writes unless it is
+// built concurrently. Every step is idempotent. The configured query
+// timeout is lifted for the run; ctx bounds it instead.
+func Migrate(ctx context.Context, name string) error {
+	var (
+		db  *bun.DB
+		err error
+	)
+	switch name {
+	case "mysql":
+		cfg := config.GetConfig().Database.MySQL
+		cfg.QueryTimeout = ""
+		db, err = dialMySQL(ctx, cfg)
+	case "postgres":
+		cfg := config.GetConfig().Database.Postgres
+		cfg.QueryTimeout = ""
+		db, err = dialPostgres(ctx, cfg)
+	default:
+		return fmt.Errorf("unknown database %q", name)
+	}
+	if err != nil {
+		return fmt.Errorf("connect %s: %w", name, err)
+	}
+	defer db.Close()
+
+	if err := migrateSchema(ctx, db); err != nil {
+		return fmt.Errorf("migrate %s: %w", name, err)
+	}
+	return nil
+}
+
+// schemaModels are created when their table is missing
+var schemaModels = []any{
+	(*model.User)(nil),
+	(*model.APIKey)(nil),
+}
+
+// migrateSchema creates the tables of schemaModels if they do not exist yet,
+// then upgrades older users tables. The unique constraint on api_keys.prefix
+// doubles as the lookup index.
+func migrateSchema(ctx context.Context, db *bun.DB) error {
+	for _, m := range schemaModels {
+		if _, err := db.NewCreateTable().Model(m).IfNotExists().Exec(ctx); err != nil {
+			return err
+		}
+	}
+	if err := addRoleColumn(ctx, db); err != nil {
+		return err
+	}
+	return createIndexes(ctx, db)
+}
+
+// userIndexes back the keyset pagination of listUsers, which orders by the
+// sort column with id as tie breaker
+var userIndexes = map[string][]string{
+	"users_name_id_idx":       {"name", "id"},
+	"users_created_at_id_idx": {"created_at", "id"},
+}
+
+// createIndexes creates userIndexes without blocking writes. PostgreSQL
+// builds them CONCURRENTLY; a build that was interrupted leaves an invalid
+// index behind, which is dropped and rebuilt. InnoDB builds secondary
+// indexes online, but MySQL has no CREATE INDEX IF NOT EXISTS, so its
+// "duplicate key name" error (1061) is ignored instead.
+func createIndexes(ctx context.Context, db *bun.DB) error {
+	pg := db.Dialect().Name() == dialect.PG
+
+	for name, columns := range userIndexes {
+		q := db.NewCreateIndex().Model((*model.User)(nil)).Index(name).Column(columns...)
+		if pg {
+			if err := dropInvalidIndex(ctx, db, name); err != nil {
+				return err
+			}
+			q = q.Concurrently().IfNotExists()
+		}
+
+		_, err := q.Exec(ctx)
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1061 {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropInvalidIndex drops a PostgreSQL index left invalid by an interrupted
+// concurrent build, which IF NOT EXISTS would otherwise keep
+func dropInvalidIndex(ctx context.Context, db *bun.DB, name string) error {
+	var invalid bool
+	err := db.NewRaw(
+		"SELECT EXISTS (SELECT 1 FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid WHERE c.relname = ? AND NOT i.indisvalid)",
+		name,
+	).Scan(ctx, &invalid)
+	if err != nil || !invalid {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+name)
+	return err
+}
+
+// addRoleColumn upgrades users tables created before roles existed. MySQL has
+// no ADD COLUMN IF NOT EXISTS, so probe the column first.
+func addRoleColumn(ctx context.Context, db *bun.DB) error {
+	if _, err := db.NewSelect().Model((*model.User)(nil)).Column("role").Limit(1).Exec(ctx); err == nil {
+		return nil
+	}
+	_, err := db.ExecContext(ctx, "ALTER TABLE users ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'viewer'")
+	return err
+}
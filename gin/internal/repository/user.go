@@ -6,10 +6,12 @@ import (
 	"github.com/yizhinailong/demo/gin/internal/model"
 )
 
+// UserRepository errors match ErrNotFound for missing users and ErrDuplicate
+// for an email that is already taken
 type UserRepository interface {
 	Create(ctx context.Context, user *model.User) error
 	GetByID(ctx context.Context, id int64) (*model.User, error)
 	Update(ctx context.Context, user *model.User) error
 	Delete(ctx context.Context, id int64) error
-	List(ctx context.Context) ([]*model.User, error)
+	List(ctx context.Context, opts UserListOptions) (*UserPage, error)
 }
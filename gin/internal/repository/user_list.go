@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/yizhinailong/demo/gin/internal/model"
+)
+
+// Columns users can be sorted by
+const (
+	UserSortID        = "id"
+	UserSortName      = "name"
+	UserSortCreatedAt = "created_at"
+)
+
+// UserSorts lists the valid UserListOptions.Sort values
+var UserSorts = []string{UserSortID, UserSortName, UserSortCreatedAt}
+
+// ErrInvalidCursor is returned for cursors that are malformed or were issued
+// for a different sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// UserListOptions 用户列表查询选项，全部下推到 SQL
+type UserListOptions struct {
+	// Limit 为 0 时不限制行数
+	Limit int
+	// Offset is ignored when After is set
+	Offset int
+	// After continues a listing after the row the cursor points at (keyset
+	// pagination), which stays fast at any depth unlike Offset
+	After *Cursor
+	Sort  string
+	Desc  bool
+
+	NamePrefix  string
+	EmailDomain string
+	// CreatedFrom and CreatedTo bound created_at to [from, to); zero is open
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+
+	// CountTotal also counts every row matching the filters
+	CountTotal bool
+}
+
+// UserPage is one page of a user listing
+type UserPage struct {
+	Users []*model.User
+	// Next points after the last user, nil on the last page
+	Next *Cursor
+	// Total is -1 unless CountTotal was set
+	Total int
+}
+
+// Cursor is the position of a row in a sorted listing: its sort column value
+// and ID, the tie breaker.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+// Encode returns the cursor as an opaque URL-safe string
+func (c *Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a string returned by Cursor.Encode
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if _, err := c.value(); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// value converts Value back to the type of the sort column
+func (c *Cursor) value() (any, error) {
+	switch c.Sort {
+	case UserSortID:
+		return c.ID, nil
+	case UserSortName:
+		return c.Value, nil
+	case UserSortCreatedAt:
+		return time.Parse(time.RFC3339Nano, c.Value)
+	default:
+		return nil, fmt.Errorf("unknown sort %q", c.Sort)
+	}
+}
+
+func cursorAt(user *model.User, sort string, desc bool) *Cursor {
+	c := &Cursor{Sort: sort, Desc: desc, ID: user.ID}
+	switch sort {
+	case UserSortName:
+		c.Value = user.Name
+	case UserSortCreatedAt:
+		c.Value = user.CreatedAt.UTC().Format(time.RFC3339Nano)
+	default:
+		c.Value = strconv.FormatInt(user.ID, 10)
+	}
+	return c
+}
+
+// listUsers runs a listing for both dialects. One extra row is fetched to
+// tell whether another page follows.
+func listUsers(ctx context.Context, db *bun.DB, opts UserListOptions) (*UserPage, error) {
+	page := &UserPage{Total: -1}
+
+	if opts.CountTotal {
+		total, err := filterUsers(db.NewSelect().Model((*model.User)(nil)), opts).Count(ctx)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = total
+	}
+
+	var users []*model.User
+	if err := userListQuery(db.NewSelect().Model(&users), opts).Scan(ctx); err != nil {
+		return nil, err
+	}
+
+	if opts.Limit > 0 && len(users) > opts.Limit {
+		users = users[:opts.Limit]
+		page.Next = cursorAt(users[len(users)-1], sortColumn(opts.Sort), opts.Desc)
+	}
+	page.Users = users
+	return page, nil
+}
+
+// userListQuery adds filters, keyset position, order and limit to q
+func userListQuery(q *bun.SelectQuery, opts UserListOptions) *bun.SelectQuery {
+	q = filterUsers(q, opts)
+
+	sort := sortColumn(opts.Sort)
+	cmp, dir := ">", "ASC"
+	if opts.Desc {
+		cmp, dir = "<", "DESC"
+	}
+
+	if opts.After != nil {
+		v, _ := opts.After.value()
+		if sort == UserSortID {
+			q = q.Where("?TableAlias.id "+cmp+" ?", opts.After.ID)
+		} else {
+			col := bun.Ident(sort)
+			q = q.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+				return q.Where("?TableAlias.? "+cmp+" ?", col, v).
+					WhereOr("?TableAlias.? = ? AND ?TableAlias.id "+cmp+" ?", col, v, opts.After.ID)
+			})
+		}
+	} else if opts.Offset > 0 {
+		q = q.Offset(opts.Offset)
+	}
+
+	q = q.OrderExpr("?TableAlias.? "+dir, bun.Ident(sort))
+	if sort != UserSortID {
+		q = q.OrderExpr("?TableAlias.id " + dir)
+	}
+
+	if opts.Limit > 0 {
+		q = q.Limit(opts.Limit + 1)
+	}
+	return q
+}
+
+// filterUsers applies the filters shared by the listing and its count
+func filterUsers(q *bun.SelectQuery, opts UserListOptions) *bun.SelectQuery {
+	if opts.NamePrefix != "" {
+		q = q.Where("?TableAlias.name LIKE ?", escapeLike(opts.NamePrefix)+"%")
+	}
+	if opts.EmailDomain != "" {
+		q = q.Where("LOWER(?TableAlias.email) LIKE ?", "%@"+escapeLike(strings.ToLower(opts.EmailDomain)))
+	}
+	if !opts.CreatedFrom.IsZero() {
+		q = q.Where("?TableAlias.created_at >= ?", opts.CreatedFrom)
+	}
+	if !opts.CreatedTo.IsZero() {
+		q = q.Where("?TableAlias.created_at < ?", opts.CreatedTo)
+	}
+	return q
+}
+
+func sortColumn(sort string) string {
+	if sort == "" {
+		return UserSortID
+	}
+	return sort
+}
+
+// escapeLike escapes the LIKE wildcards; backslash is the default escape
+// character in MySQL and PostgreSQL alike
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -4,62 +4,84 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/uptrace/bun"
 	"github.com/yizhinailong/demo/gin/internal/model"
 )
 
 type userPostgresRepo struct {
-	db *bun.DB
+	conn *Connection
 }
 
 // NewUserPostgresRepository creates a new PostgreSQL user repository
 func NewUserPostgresRepository() UserRepository {
-	db := GetPostgresDB()
-	if db == nil {
-		return nil
-	}
-	return &userPostgresRepo{db: db}
+	return &userPostgresRepo{conn: Postgres()}
 }
 
 func (r *userPostgresRepo) Create(ctx context.Context, user *model.User) error {
-	_, err := r.db.NewInsert().Model(user).Exec(ctx)
+	db, err := r.conn.DB(ctx)
 	if err != nil {
-		return fmt.Errorf("创建用户失败: %w", err)
+		return err
+	}
+
+	_, err = db.NewInsert().Model(user).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("创建用户失败: %w", translateError(err))
 	}
 	return nil
 }
 
 func (r *userPostgresRepo) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	db, err := r.conn.DB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var user model.User
-	err := r.db.NewSelect().Model(&user).Where("id = ?", id).Scan(ctx)
+	err = db.NewSelect().Model(&user).Where("id = ?", id).Scan(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("查询用户失败: %w", err)
+		return nil, fmt.Errorf("查询用户失败: %w", translateError(err))
 	}
 	return &user, nil
 }
 
 func (r *userPostgresRepo) Update(ctx context.Context, user *model.User) error {
-	_, err := r.db.NewUpdate().Model(user).WherePK().Exec(ctx)
+	db, err := r.conn.DB(ctx)
 	if err != nil {
-		return fmt.Errorf("更新用户失败: %w", err)
+		return err
+	}
+
+	_, err = db.NewUpdate().Model(user).WherePK().Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("更新用户失败: %w", translateError(err))
 	}
 	return nil
 }
 
 func (r *userPostgresRepo) Delete(ctx context.Context, id int64) error {
+	db, err := r.conn.DB(ctx)
+	if err != nil {
+		return err
+	}
+
 	user := &model.User{ID: id}
-	_, err := r.db.NewDelete().Model(user).WherePK().Exec(ctx)
+	res, err := db.NewDelete().Model(user).WherePK().Exec(ctx)
 	if err != nil {
+		return fmt.Errorf("删除用户失败: %w", translateError(err))
+	}
+	if err := requireAffected(res); err != nil {
 		return fmt.Errorf("删除用户失败: %w", err)
 	}
 	return nil
 }
 
-func (r *userPostgresRepo) List(ctx context.Context) ([]*model.User, error) {
-	var users []*model.User
-	err := r.db.NewSelect().Model(&users).Scan(ctx)
+func (r *userPostgresRepo) List(ctx context.Context, opts UserListOptions) (*UserPage, error) {
+	db, err := r.conn.DB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := listUsers(ctx, db, opts)
 	if err != nil {
-		return nil, fmt.Errorf("查询用户列表失败: %w", err)
+		return nil, fmt.Errorf("查询用户列表失败: %w", translateError(err))
 	}
-	return users, nil
+	return page, nil
 }
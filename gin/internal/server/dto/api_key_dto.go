@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/yizhinailong/demo/gin/internal/model"
+)
+
+type IssueAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes"`
+	// ExpiresIn is a duration such as "720h"; empty means the key never expires
+	ExpiresIn string `json:"expires_in"`
+}
+
+type APIKeyResponse struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Prefix     string    `json:"prefix"`
+	Scopes     []string  `json:"scopes"`
+	ExpiresAt  time.Time `json:"expires_at,omitzero"`
+	LastUsedAt time.Time `json:"last_used_at,omitzero"`
+	RevokedAt  time.Time `json:"revoked_at,omitzero"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// IssueAPIKeyResponse is the only response that ever contains the secret key
+type IssueAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+func NewAPIKeyResponse(k *model.APIKey) APIKeyResponse {
+	scopes := k.ScopeList()
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     scopes,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
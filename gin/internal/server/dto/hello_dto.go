@@ -0,0 +1,9 @@
+package dto
+
+// PrintResponse echoes what GET /print/:name received
+type PrintResponse struct {
+	Name   string `json:"name"`
+	Query  string `json:"query"`
+	Header string `json:"header"`
+	Body   string `json:"body"`
+}
@@ -0,0 +1,76 @@
+package dto
+
+import "net/http"
+
+// Machine-readable error codes. They are stable, unlike messages, and are
+// sent as Response.Code or Problem.Code.
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeValidationFailed = "validation_failed"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodeForbidden        = "forbidden"
+	CodePayloadTooLarge  = "payload_too_large"
+	CodeRateLimited      = "rate_limited"
+	CodeTimeout          = "timeout"
+	CodeUnavailable      = "unavailable"
+	CodeInternal         = "internal_error"
+)
+
+// Response is the body of every JSON response. Successful responses carry
+// Data; failed ones carry Code and, for invalid input, Errors. RequestID
+// echoes X-Request-ID so a response can be found in the server logs.
+type Response[T any] struct {
+	Data      T            `json:"data,omitzero"`
+	Code      string       `json:"code,omitempty"`
+	Message   string       `json:"message,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// FieldError points at one invalid request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a failure on its way to the client, rendered either as a Response
+// or as a Problem
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Errors  []FieldError
+}
+
+// Problem is an RFC 7807 problem details object. Code, Errors and RequestID
+// are extension members carrying the same values as in Response.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// NewProblem converts e; the type is typeBase followed by the error code, or
+// "about:blank" when typeBase is empty
+func NewProblem(e Error, typeBase, instance, requestID string) Problem {
+	typ := "about:blank"
+	if typeBase != "" {
+		typ = typeBase + e.Code
+	}
+	return Problem{
+		Type:      typ,
+		Title:     http.StatusText(e.Status),
+		Status:    e.Status,
+		Detail:    e.Message,
+		Instance:  instance,
+		Code:      e.Code,
+		Errors:    e.Errors,
+		RequestID: requestID,
+	}
+}
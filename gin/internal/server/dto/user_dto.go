@@ -1,26 +1,58 @@
 package dto
 
-import "github.com/yizhinailong/demo/gin/internal/model"
+import (
+	"time"
+
+	"github.com/yizhinailong/demo/gin/internal/model"
+)
 
 type CreateUserRequest struct {
-	Name     string `json:"name"`
-	Email    string `json:"email"`
+	Name     string `json:"name" binding:"required"`
+	Email    string `json:"email" binding:"required"`
+	Role     string `json:"role"`
 	Database string `json:"database"`
 }
 
-type CreateUserResponse struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
-	ID      int64  `json:"id"`
-}
-
 type GetUserRequest struct {
-	ID       int64  `json:"id"`
+	ID       int64  `json:"id" binding:"required"`
 	Database string `json:"database"`
 }
 
-type GetUserResponse struct {
-	Status  int         `json:"status"`
-	Message string      `json:"message"`
-	User    *model.User `json:"user"`
+// UpdateUserRequest replaces a user's name and email (PUT); an empty role
+// keeps the current one
+type UpdateUserRequest struct {
+	Name  string `json:"name" binding:"required"`
+	Email string `json:"email" binding:"required"`
+	Role  string `json:"role"`
+}
+
+// PatchUserRequest changes only the fields present in the body (PATCH)
+type PatchUserRequest struct {
+	Name  *string `json:"name"`
+	Email *string `json:"email"`
+	Role  *string `json:"role"`
+}
+
+// ListUsersQuery holds the query parameters of GET /users. Sort is id, name
+// or created_at, prefixed with "-" for descending order; created_from and
+// created_to are RFC 3339 timestamps.
+type ListUsersQuery struct {
+	Limit       int       `form:"limit"`
+	Offset      int       `form:"offset"`
+	Cursor      string    `form:"cursor"`
+	Sort        string    `form:"sort"`
+	NamePrefix  string    `form:"name_prefix"`
+	EmailDomain string    `form:"email_domain"`
+	CreatedFrom time.Time `form:"created_from" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedTo   time.Time `form:"created_to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Total       bool      `form:"total"`
+}
+
+// ListUsersResponse is the data of GET /users
+type ListUsersResponse struct {
+	Users []*model.User `json:"users"`
+	// NextCursor is passed as ?cursor= to fetch the next page
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Total is only present when requested with ?total=true
+	Total *int `json:"total,omitempty"`
 }
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/yizhinailong/demo/gin/internal/config"
+	"github.com/yizhinailong/demo/gin/internal/server/middleware"
+	"github.com/yizhinailong/demo/gin/pkg/logger"
+
+	router "github.com/yizhinailong/demo/gin/internal/server"
+)
+
+type AdminHandler struct{}
+
+// SetLogLevelRequest changes the global level, or a named logger's level
+// when Logger is set. Duration defaults to log.level_reset_after and may not
+// exceed log.level_max_duration.
+type SetLogLevelRequest struct {
+	Level    string `json:"level"`
+	Logger   string `json:"logger"`
+	Duration string `json:"duration"`
+}
+
+func init() {
+	router.Register(&AdminHandler{})
+}
+
+func (h *AdminHandler) RegisterRoutes(r *gin.Engine) {
+	group := r.Group("/admin", middleware.AdminAuth())
+	{
+		group.GET("/config", h.Config)
+		group.GET("/log/level", h.GetLogLevel)
+		group.PUT("/log/level", h.SetLogLevel)
+	}
+}
+
+// Config returns the effective configuration with secrets redacted
+func (h *AdminHandler) Config(c *gin.Context) {
+	middleware.Respond(c, http.StatusOK, "", config.GetConfig().Redacted())
+}
+
+// GetLogLevel reports the global level and any per-logger overrides
+func (h *AdminHandler) GetLogLevel(c *gin.Context) {
+	middleware.Respond(c, http.StatusOK, "", logger.Levels())
+}
+
+// SetLogLevel temporarily changes a log level; it reverts automatically
+func (h *AdminHandler) SetLogLevel(c *gin.Context) {
+	var request SetLogLevelRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	// zap also knows dpanic, panic and fatal; none is a level to log at
+	lvl, err := zapcore.ParseLevel(request.Level)
+	if err != nil || lvl < zapcore.DebugLevel || lvl > zapcore.ErrorLevel {
+		abortWithError(c, invalidField("level", "must be one of debug, info, warn, error, got "+strconv.Quote(request.Level)))
+		return
+	}
+
+	settings := config.GetConfig().Log
+	duration := config.ParseDuration(settings.LevelResetAfter, 15*time.Minute)
+	if request.Duration != "" {
+		duration, err = time.ParseDuration(request.Duration)
+		if err != nil || duration <= 0 {
+			abortWithError(c, invalidField("duration", "must be a positive duration such as \"30m\", got "+strconv.Quote(request.Duration)))
+			return
+		}
+		// The level must revert; an override that outlives an incident is a
+		// forgotten one
+		if limit := config.ParseDuration(settings.LevelMaxDuration, 4*time.Hour); duration > limit {
+			abortWithError(c, invalidField("duration", "must not exceed "+limit.String()+", got "+strconv.Quote(request.Duration)))
+			return
+		}
+	}
+
+	logger.SetTemporaryLevel(request.Logger, lvl, duration)
+	logger.L.Warn("Log level changed via admin endpoint",
+		zap.Stringer("level", lvl),
+		zap.String("logger", request.Logger),
+		zap.Duration("revert_after", duration),
+	)
+
+	middleware.Respond(c, http.StatusOK, "log level changed", logger.Levels())
+}
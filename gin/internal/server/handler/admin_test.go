@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yizhinailong/demo/gin/pkg/logger"
+)
+
+func TestAdminHandler_SetLogLevel(t *testing.T) {
+	router := setupTestRouter()
+	router.PUT("/admin/log/level", (&AdminHandler{}).SetLogLevel)
+	t.Cleanup(func() { logger.ResetLevel("admin_test") })
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{name: "debug for the default duration", body: `{"level":"debug","logger":"admin_test"}`, code: http.StatusOK},
+		{name: "explicit duration", body: `{"level":"warn","logger":"admin_test","duration":"30m"}`, code: http.StatusOK},
+		{name: "duration above the maximum", body: `{"level":"debug","logger":"admin_test","duration":"87600h"}`, code: http.StatusBadRequest},
+		{name: "negative duration", body: `{"level":"debug","logger":"admin_test","duration":"-1m"}`, code: http.StatusBadRequest},
+		{name: "fatal is not a log level", body: `{"level":"fatal","logger":"admin_test"}`, code: http.StatusBadRequest},
+		{name: "dpanic is not a log level", body: `{"level":"dpanic","logger":"admin_test"}`, code: http.StatusBadRequest},
+		{name: "unknown level", body: `{"level":"verbose","logger":"admin_test"}`, code: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/admin/log/level", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yizhinailong/demo/gin/internal/server/dto"
+	"github.com/yizhinailong/demo/gin/internal/server/middleware"
+	"github.com/yizhinailong/demo/gin/internal/service"
+
+	router "github.com/yizhinailong/demo/gin/internal/server"
+)
+
+// APIKeyHandler lets operators issue and revoke API keys under /admin
+type APIKeyHandler struct {
+	apiKeys service.APIKeyServiceInterface
+}
+
+func init() {
+	router.Register(&APIKeyHandler{apiKeys: service.NewAPIKeyService()})
+}
+
+func (h *APIKeyHandler) RegisterRoutes(r *gin.Engine) {
+	group := r.Group("/admin/api-keys", middleware.AdminAuth())
+	{
+		group.GET("", h.List)
+		group.POST("", h.Issue)
+		group.DELETE("/:id", h.Revoke)
+	}
+}
+
+// Issue creates a key; the secret is part of this response only
+func (h *APIKeyHandler) Issue(c *gin.Context) {
+	var request dto.IssueAPIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	input := &service.IssueAPIKeyInput{
+		Name:   request.Name,
+		Scopes: request.Scopes,
+	}
+	if request.ExpiresIn != "" {
+		d, err := time.ParseDuration(request.ExpiresIn)
+		if err != nil || d <= 0 {
+			abortWithError(c, invalidField("expires_in", "must be a positive duration such as \"720h\""))
+			return
+		}
+		input.ExpiresAt = time.Now().Add(d)
+	}
+
+	issued, err := h.apiKeys.Issue(c.Request.Context(), input)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	middleware.Respond(c, http.StatusCreated, "API key issued", dto.IssueAPIKeyResponse{
+		APIKeyResponse: dto.NewAPIKeyResponse(issued.APIKey),
+		Key:            issued.Key,
+	})
+}
+
+// List returns all keys without their secrets
+func (h *APIKeyHandler) List(c *gin.Context) {
+	keys, err := h.apiKeys.List(c.Request.Context())
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	out := make([]dto.APIKeyResponse, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, dto.NewAPIKeyResponse(k))
+	}
+	middleware.Respond(c, http.StatusOK, "", out)
+}
+
+// Revoke disables a key; revoking an unknown or already revoked key is a 404
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		abortWithError(c, invalidField("id", "must be an integer, got "+strconv.Quote(c.Param("id"))))
+		return
+	}
+
+	if err := h.apiKeys.Revoke(c.Request.Context(), id); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
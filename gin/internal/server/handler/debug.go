@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http/pprof"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yizhinailong/demo/gin/internal/config"
+	"github.com/yizhinailong/demo/gin/internal/server/middleware"
+
+	router "github.com/yizhinailong/demo/gin/internal/server"
+)
+
+// DebugHandler exposes net/http/pprof when server.debug_endpoints is on. The
+// endpoints reveal the command line, flags included, and profiles hold a
+// request open, so they sit behind the admin token.
+type DebugHandler struct{}
+
+func init() {
+	router.Register(&DebugHandler{})
+}
+
+func (h *DebugHandler) RegisterRoutes(r *gin.Engine) {
+	if !config.GetConfig().Server.DebugEndpoints {
+		return
+	}
+
+	group := r.Group("/debug/pprof", middleware.AdminAuth())
+	{
+		group.GET("/*name", h.Pprof)
+		group.POST("/*name", h.Pprof)
+	}
+}
+
+// Pprof dispatches to the pprof handler named by the path
+func (h *DebugHandler) Pprof(c *gin.Context) {
+	switch strings.TrimPrefix(c.Param("name"), "/") {
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		pprof.Index(c.Writer, c.Request)
+	}
+}
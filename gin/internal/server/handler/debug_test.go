@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yizhinailong/demo/gin/internal/config"
+	"github.com/yizhinailong/demo/gin/internal/server/middleware"
+)
+
+func TestDebugHandler_RequiresAdminToken(t *testing.T) {
+	prev := config.GetConfig()
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	cfg.Server.DebugEndpoints = true
+	cfg.Admin.Token = "secret"
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(prev) })
+
+	r := setupTestRouter()
+	(&DebugHandler{}).RegisterRoutes(r)
+
+	do := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/debug/pprof/cmdline", nil)
+		if token != "" {
+			req.Header.Set(middleware.AdminTokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, do(""))
+	assert.Equal(t, http.StatusUnauthorized, do("wrong"))
+	assert.Equal(t, http.StatusOK, do("secret"))
+}
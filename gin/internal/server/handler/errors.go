@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+
+	"github.com/yizhinailong/demo/gin/internal/repository"
+	"github.com/yizhinailong/demo/gin/internal/server/dto"
+	"github.com/yizhinailong/demo/gin/internal/server/middleware"
+	"github.com/yizhinailong/demo/gin/internal/service"
+	"github.com/yizhinailong/demo/gin/pkg/logger"
+)
+
+func init() {
+	// Report binding errors under the JSON, form or query name of the field
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				if name, _, _ := strings.Cut(f.Tag.Get(tag), ","); name != "" && name != "-" {
+					return name
+				}
+			}
+			return f.Name
+		})
+	}
+}
+
+// translateError is the single place deciding how a service or repository
+// error surfaces over HTTP. Server-side failures get a generic message; their
+// details only go to the log.
+func translateError(ctx context.Context, err error) dto.Error {
+	var verr *service.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		fields := make([]dto.FieldError, len(verr.Fields))
+		for i, fe := range verr.Fields {
+			fields[i] = dto.FieldError{Field: fe.Field, Message: fe.Message}
+		}
+		return dto.Error{Status: http.StatusBadRequest, Code: dto.CodeValidationFailed, Message: "validation failed", Errors: fields}
+	case errors.Is(err, repository.ErrNotFound):
+		return dto.Error{Status: http.StatusNotFound, Code: dto.CodeNotFound, Message: "resource not found"}
+	case errors.Is(err, repository.ErrDuplicate):
+		return dto.Error{Status: http.StatusConflict, Code: dto.CodeConflict, Message: "resource already exists"}
+	case errors.Is(err, service.ErrRoleChangeForbidden):
+		return dto.Error{Status: http.StatusForbidden, Code: dto.CodeForbidden, Message: err.Error()}
+	// The driver may report an expired deadline as a plain connection error,
+	// hence the ctx check
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return dto.Error{Status: http.StatusGatewayTimeout, Code: dto.CodeTimeout, Message: "request timed out"}
+	case errors.Is(err, repository.ErrNotConnected), errors.Is(err, context.Canceled):
+		return dto.Error{Status: http.StatusServiceUnavailable, Code: dto.CodeUnavailable, Message: "database temporarily unavailable"}
+	default:
+		return dto.Error{Status: http.StatusInternalServerError, Code: dto.CodeInternal, Message: "internal server error"}
+	}
+}
+
+// abortWithError writes the translation of err and logs server-side failures
+func abortWithError(c *gin.Context, err error) {
+	ctx := c.Request.Context()
+	failure := translateError(ctx, err)
+	if failure.Status >= http.StatusInternalServerError {
+		logger.FromContext(ctx).Error("Request failed", zap.Int("status", failure.Status), zap.Error(err))
+	}
+	middleware.Abort(c, failure)
+}
+
+// abortWithBindError answers 400 for a request that could not be bound,
+// listing the failed validation rules per field when there are any
+func abortWithBindError(c *gin.Context, err error) {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		middleware.Abort(c, dto.Error{
+			Status:  http.StatusBadRequest,
+			Code:    dto.CodeInvalidRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	fields := make([]dto.FieldError, len(verrs))
+	for i, fe := range verrs {
+		fields[i] = dto.FieldError{Field: fe.Field(), Message: "failed on the '" + fe.Tag() + "' rule"}
+	}
+	middleware.Abort(c, dto.Error{
+		Status:  http.StatusBadRequest,
+		Code:    dto.CodeValidationFailed,
+		Message: "validation failed",
+		Errors:  fields,
+	})
+}
+
+// invalidField reports a malformed path, query or header value
+func invalidField(field, message string) error {
+	return &service.ValidationError{Fields: []service.FieldError{{Field: field, Message: message}}}
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/yizhinailong/demo/gin/internal/config"
+	"github.com/yizhinailong/demo/gin/internal/repository"
+	"github.com/yizhinailong/demo/gin/internal/server/middleware"
+	"github.com/yizhinailong/demo/gin/pkg/logger"
+
+	router "github.com/yizhinailong/demo/gin/internal/server"
+)
+
+// CheckFunc probes a single dependency and returns nil when it is healthy.
+type CheckFunc func(ctx context.Context) error
+
+type HealthHandler struct {
+	checks   map[string]CheckFunc
+	settings func() config.HealthConfig
+}
+
+// HealthStatus is the data of /healthz and /readyz; Checks is only set by
+// /readyz.
+type HealthStatus struct {
+	Status string                      `json:"status"`
+	Checks map[string]DependencyStatus `json:"checks,omitempty"`
+}
+
+// DependencyStatus is the readiness result of a single dependency. /readyz
+// is anonymous, so why a dependency is down is only logged: driver errors
+// name internal hosts and ports.
+type DependencyStatus struct {
+	Status   string `json:"status"`
+	Required bool   `json:"required"`
+	Latency  string `json:"latency"`
+}
+
+func init() {
+	router.Register(NewHealthHandler())
+}
+
+// NewHealthHandler creates a health handler probing the configured databases
+func NewHealthHandler() *HealthHandler {
+	return &HealthHandler{
+		checks: map[string]CheckFunc{
+			"mysql":    pingDB(repository.MySQL()),
+			"postgres": pingDB(repository.Postgres()),
+		},
+		settings: func() config.HealthConfig {
+			return config.GetConfig().Health
+		},
+	}
+}
+
+func pingDB(conn *repository.Connection) CheckFunc {
+	return func(ctx context.Context) error {
+		db, err := conn.DB(ctx)
+		if err != nil {
+			return err
+		}
+		return db.PingContext(ctx)
+	}
+}
+
+func (h *HealthHandler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/healthz", h.Liveness)
+	r.GET("/readyz", h.Readiness)
+}
+
+// Liveness reports that the process is up and serving requests
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	middleware.Respond(c, http.StatusOK, "", HealthStatus{Status: "ok"})
+}
+
+// Readiness pings every dependency and returns 503 if a required one is down
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	settings := h.settings()
+	timeout := config.ParseDuration(settings.Timeout, 2*time.Second)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]DependencyStatus, len(h.checks))
+	)
+
+	for name, check := range h.checks {
+		wg.Go(func() {
+			start := time.Now()
+			err := check(ctx)
+
+			result := DependencyStatus{
+				Status:   "up",
+				Required: slices.Contains(settings.Required, name),
+				Latency:  time.Since(start).String(),
+			}
+			if err != nil {
+				result.Status = "down"
+				logger.FromContext(c.Request.Context()).Warn("Dependency check failed",
+					zap.String("dependency", name),
+					zap.Bool("required", result.Required),
+					zap.Error(err),
+				)
+			}
+
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+
+	status, code := "ok", http.StatusOK
+	for _, result := range results {
+		if result.Required && result.Status != "up" {
+			status, code = "unavailable", http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	// A 503 still carries the per-dependency report, so it is not sent as an
+	// error body
+	middleware.Respond(c, code, "", HealthStatus{Status: status, Checks: results})
+}
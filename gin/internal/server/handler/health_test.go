@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yizhinailong/demo/gin/internal/config"
+	"github.com/yizhinailong/demo/gin/internal/server/dto"
+)
+
+func newTestHealthHandler(required []string, mysqlErr, postgresErr error) *HealthHandler {
+	return &HealthHandler{
+		checks: map[string]CheckFunc{
+			"mysql":    func(ctx context.Context) error { return mysqlErr },
+			"postgres": func(ctx context.Context) error { return postgresErr },
+		},
+		settings: func() config.HealthConfig {
+			return config.HealthConfig{Required: required, Timeout: "1s"}
+		},
+	}
+}
+
+func TestHealthHandler_Liveness(t *testing.T) {
+	router := setupTestRouter()
+	newTestHealthHandler(nil, nil, nil).RegisterRoutes(router)
+
+	req := httptest.NewRequest("GET", "/healthz", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHealthHandler_Readiness(t *testing.T) {
+	t.Run("all dependencies up", func(t *testing.T) {
+		router := setupTestRouter()
+		newTestHealthHandler([]string{"mysql", "postgres"}, nil, nil).RegisterRoutes(router)
+
+		req := httptest.NewRequest("GET", "/readyz", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var envelope dto.Response[HealthStatus]
+		err := json.Unmarshal(w.Body.Bytes(), &envelope)
+		response := envelope.Data
+		assert.NoError(t, err)
+		assert.Equal(t, "ok", response.Status)
+		assert.Equal(t, "up", response.Checks["mysql"].Status)
+		assert.Equal(t, "up", response.Checks["postgres"].Status)
+	})
+
+	t.Run("required dependency down", func(t *testing.T) {
+		router := setupTestRouter()
+		newTestHealthHandler([]string{"mysql"}, assert.AnError, nil).RegisterRoutes(router)
+
+		req := httptest.NewRequest("GET", "/readyz", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		var envelope dto.Response[HealthStatus]
+		err := json.Unmarshal(w.Body.Bytes(), &envelope)
+		response := envelope.Data
+		assert.NoError(t, err)
+		assert.Equal(t, "unavailable", response.Status)
+		assert.Equal(t, "down", response.Checks["mysql"].Status)
+		assert.True(t, response.Checks["mysql"].Required)
+		// The cause is logged, never sent to the anonymous caller
+		assert.NotContains(t, w.Body.String(), assert.AnError.Error())
+	})
+
+	t.Run("optional dependency down", func(t *testing.T) {
+		router := setupTestRouter()
+		newTestHealthHandler([]string{"mysql"}, nil, assert.AnError).RegisterRoutes(router)
+
+		req := httptest.NewRequest("GET", "/readyz", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
@@ -5,6 +5,10 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/yizhinailong/demo/gin/internal/server/dto"
+	"github.com/yizhinailong/demo/gin/internal/server/middleware"
+	"github.com/yizhinailong/demo/gin/pkg/logger"
+
 	router "github.com/yizhinailong/demo/gin/internal/server"
 )
 
@@ -23,19 +27,14 @@ func (h *HelloHandler) RegisterRoutes(r *gin.Engine) {
 type HelloHandler struct{}
 
 func (h *HelloHandler) HelloWorld(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "hello world",
-		"status":  "success",
-	})
+	middleware.Respond[any](c, http.StatusOK, "hello world", nil)
 }
 
 func (h *HelloHandler) Print(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "print",
-		"status":  "success",
-		"data":    c.Param("name"),
-		"query":   c.Query("name"),
-		"header":  c.GetHeader("Authorization"),
-		"body":    c.PostForm("name"),
+	middleware.Respond(c, http.StatusOK, "print", dto.PrintResponse{
+		Name:   c.Param("name"),
+		Query:  c.Query("name"),
+		Header: logger.Redact(c.GetHeader("Authorization")),
+		Body:   c.PostForm("name"),
 	})
 }
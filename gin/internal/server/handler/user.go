@@ -1,11 +1,16 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/yizhinailong/demo/gin/internal/model"
 	"github.com/yizhinailong/demo/gin/internal/server/dto"
+	"github.com/yizhinailong/demo/gin/internal/server/middleware"
 	"github.com/yizhinailong/demo/gin/internal/service"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -13,85 +18,365 @@ import (
 	router "github.com/yizhinailong/demo/gin/internal/server"
 )
 
+// DatabaseHeader selects the database of the user routes, like ?database=
+const DatabaseHeader = "X-Database"
+
 type UserHandler struct {
 	userService service.UserServiceInterface
 }
 
 func init() {
 	// Initialize repository and service
-	userService := service.NewUserService()
+	userService := service.DefaultUserService()
 
 	// Register handler with initialized service
 	router.Register(&UserHandler{userService: userService})
 }
 
+// legacyRoutesDeprecated is when POST /users/create and GET /users/get
+// became aliases of the resource routes
+var legacyRoutesDeprecated = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
 func (h *UserHandler) RegisterRoutes(r *gin.Engine) {
 	group := r.Group("/users")
 	{
-		group.POST("/create", h.CreateUser)
-		group.GET("/get", h.GetUser)
+		group.GET("", h.ListUsers)
+		group.POST("", h.CreateUser)
+		group.GET("/:id", h.GetUser)
+		group.PUT("/:id", h.UpdateUser)
+		group.PATCH("/:id", h.PatchUser)
+		group.DELETE("/:id", h.DeleteUser)
+	}
+
+	// Deprecated aliases; GET /users/get reads the ID from a JSON body, which
+	// proxies and browsers drop
+	legacy := r.Group("/users")
+	{
+		legacy.POST("/create", middleware.Deprecated(legacyRoutesDeprecated, "/users"), h.LegacyCreateUser)
+		legacy.GET("/get", middleware.Deprecated(legacyRoutesDeprecated, ""), h.LegacyGetUser)
 	}
 }
 
+// Rules requires a token with the users:read or users:write scope. Only
+// admins create and delete users; operators and admins read, list and update
+// any user, everyone else only reads and updates their own record in
+// auth.users.database. API keys are held to the scopes alone.
+func (h *UserHandler) Rules() []router.Rule {
+	return []router.Rule{
+		{
+			Method: http.MethodGet, Path: "/users",
+			Scopes: []string{"users:read"},
+			Roles:  []model.Role{model.RoleAdmin, model.RoleOperator},
+		},
+		{
+			Method: http.MethodPost, Path: "/users",
+			Scopes: []string{"users:write"},
+			Roles:  []model.Role{model.RoleAdmin},
+		},
+		{
+			Method: http.MethodGet, Path: "/users/:id",
+			Scopes: []string{"users:read"},
+			Roles:  []model.Role{model.RoleAdmin, model.RoleOperator},
+			Owner:  router.Param("id"), Database: ownerDatabase,
+		},
+		{
+			Method: http.MethodPut, Path: "/users/:id",
+			Scopes: []string{"users:write"},
+			Roles:  []model.Role{model.RoleAdmin, model.RoleOperator},
+			Owner:  router.Param("id"), Database: ownerDatabase,
+		},
+		{
+			Method: http.MethodPatch, Path: "/users/:id",
+			Scopes: []string{"users:write"},
+			Roles:  []model.Role{model.RoleAdmin, model.RoleOperator},
+			Owner:  router.Param("id"), Database: ownerDatabase,
+		},
+		{
+			Method: http.MethodDelete, Path: "/users/:id",
+			Scopes: []string{"users:write"},
+			Roles:  []model.Role{model.RoleAdmin},
+		},
+		{
+			Method: http.MethodPost, Path: "/users/create",
+			Scopes: []string{"users:write"},
+			Roles:  []model.Role{model.RoleAdmin},
+		},
+		{
+			Method: http.MethodGet, Path: "/users/get",
+			Scopes: []string{"users:read"},
+			Roles:  []model.Role{model.RoleAdmin, model.RoleOperator},
+			Owner:  router.JSONField("id"), Database: legacyOwnerDatabase,
+		},
+	}
+}
+
+// CreateUser handles POST /users and answers 201 with the user's URL in Location
 func (h *UserHandler) CreateUser(c *gin.Context) {
+	h.createUser(c, http.StatusCreated)
+}
+
+// LegacyCreateUser handles the deprecated POST /users/create, which answers 200
+func (h *UserHandler) LegacyCreateUser(c *gin.Context) {
+	h.createUser(c, http.StatusOK)
+}
+
+func (h *UserHandler) createUser(c *gin.Context, status int) {
 	var resquest dto.CreateUserRequest
 	if err := c.ShouldBindJSON(&resquest); err != nil {
-		c.JSON(http.StatusBadRequest, dto.CreateUserResponse{
-			Status:  http.StatusBadRequest,
-			Message: err.Error(),
-			ID:      -1,
-		})
+		abortWithBindError(c, err)
+		return
+	}
+
+	database, err := userDatabase(c, resquest.Database)
+	if err != nil {
+		abortWithError(c, err)
 		return
 	}
 
 	input := &service.CreateUserInput{
 		Name:     resquest.Name,
 		Email:    resquest.Email,
-		Database: resquest.Database,
+		Role:     resquest.Role,
+		Database: database,
 	}
 
-	if user, err := h.userService.CreateUser(c, input); err != nil {
-		c.JSON(http.StatusInternalServerError, dto.CreateUserResponse{
-			Status:  http.StatusInternalServerError,
-			Message: err.Error(),
-			ID:      -1,
-		})
-	} else {
-		c.JSON(http.StatusOK, dto.CreateUserResponse{
-			Status:  http.StatusOK,
-			Message: "user successfully created",
-			ID:      user.ID,
-		})
+	user, err := h.userService.CreateUser(c.Request.Context(), input)
+	if err != nil {
+		abortWithError(c, err)
+		return
 	}
+
+	c.Header("Location", "/users/"+strconv.FormatInt(user.ID, 10))
+	middleware.Respond(c, status, "user successfully created", user)
 }
 
+// GetUser handles GET /users/:id
 func (h *UserHandler) GetUser(c *gin.Context) {
+	id, err := userID(c)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	h.getUser(c, id, "")
+}
+
+// LegacyGetUser handles the deprecated GET /users/get, which takes the ID
+// and database from a JSON body
+func (h *UserHandler) LegacyGetUser(c *gin.Context) {
 	var resquest dto.GetUserRequest
 	if err := c.ShouldBindJSON(&resquest); err != nil {
-		c.JSON(http.StatusBadRequest, dto.GetUserResponse{
-			Status:  http.StatusBadRequest,
-			Message: err.Error(),
-			User:    nil,
-		})
+		abortWithBindError(c, err)
+		return
+	}
+
+	h.getUser(c, resquest.ID, resquest.Database)
+}
+
+func (h *UserHandler) getUser(c *gin.Context, id int64, bodyDatabase string) {
+	database, err := userDatabase(c, bodyDatabase)
+	if err != nil {
+		abortWithError(c, err)
 		return
 	}
 
 	input := &service.GetUserInput{
-		ID:       resquest.ID,
-		Database: resquest.Database,
-	}
-
-	if user, err := h.userService.GetUser(c, input); err != nil {
-		c.JSON(http.StatusInternalServerError, dto.GetUserResponse{
-			Status:  http.StatusInternalServerError,
-			Message: err.Error(),
-			User:    nil,
-		})
-	} else {
-		c.JSON(http.StatusOK, dto.GetUserResponse{
-			Status:  http.StatusOK,
-			Message: "user found",
-			User:    user,
-		})
+		ID:       id,
+		Database: database,
+	}
+
+	user, err := h.userService.GetUser(c.Request.Context(), input)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	middleware.Respond(c, http.StatusOK, "user found", user)
+}
+
+// UpdateUser handles PUT /users/:id
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	var request dto.UpdateUserRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	input := &service.UpdateUserInput{
+		Name:  &request.Name,
+		Email: &request.Email,
+	}
+	if request.Role != "" {
+		input.Role = &request.Role
+	}
+
+	h.updateUser(c, input)
+}
+
+// PatchUser handles PATCH /users/:id
+func (h *UserHandler) PatchUser(c *gin.Context) {
+	var request dto.PatchUserRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	h.updateUser(c, &service.UpdateUserInput{
+		Name:  request.Name,
+		Email: request.Email,
+		Role:  request.Role,
+	})
+}
+
+func (h *UserHandler) updateUser(c *gin.Context, input *service.UpdateUserInput) {
+	id, err := userID(c)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	database, err := userDatabase(c, "")
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	input.ID = id
+	input.Database = database
+
+	user, err := h.userService.UpdateUser(c.Request.Context(), input)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	middleware.Respond(c, http.StatusOK, "user updated", user)
+}
+
+// DeleteUser handles DELETE /users/:id and answers 204
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id, err := userID(c)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	database, err := userDatabase(c, "")
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	input := &service.DeleteUserInput{
+		ID:       id,
+		Database: database,
+	}
+
+	if err := h.userService.DeleteUser(c.Request.Context(), input); err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListUsers handles GET /users; see dto.ListUsersQuery for the parameters
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	var query dto.ListUsersQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		abortWithBindError(c, err)
+		return
+	}
+
+	database, err := userDatabase(c, "")
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	input := &service.ListUsersInput{
+		Database:    database,
+		Limit:       query.Limit,
+		Offset:      query.Offset,
+		Cursor:      query.Cursor,
+		Sort:        query.Sort,
+		NamePrefix:  query.NamePrefix,
+		EmailDomain: query.EmailDomain,
+		CreatedFrom: query.CreatedFrom,
+		CreatedTo:   query.CreatedTo,
+		CountTotal:  query.Total,
+	}
+
+	list, err := h.userService.ListUsers(c.Request.Context(), input)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	response := dto.ListUsersResponse{
+		Users:      list.Users,
+		NextCursor: list.NextCursor,
+	}
+	if response.Users == nil {
+		response.Users = []*model.User{}
+	}
+	if query.Total {
+		response.Total = &list.Total
+	}
+
+	middleware.Respond(c, http.StatusOK, "users found", response)
+}
+
+// userID parses the :id path parameter
+func userID(c *gin.Context) (int64, error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, invalidField("id", "must be an integer, got "+strconv.Quote(c.Param("id")))
+	}
+	return id, nil
+}
+
+// ownerDatabase returns the database a /users/:id request acts on, resolved
+// like the handlers do, for the owner check of Authorize. An invalid
+// selector matches no database.
+func ownerDatabase(c *gin.Context) string {
+	return resolvedDatabase(c, "")
+}
+
+// legacyOwnerDatabase is ownerDatabase for GET /users/get, which may name the
+// database in its JSON body
+func legacyOwnerDatabase(c *gin.Context) string {
+	fallback, _ := router.JSONField("database")(c)
+	return resolvedDatabase(c, fallback)
+}
+
+func resolvedDatabase(c *gin.Context, fallback string) string {
+	database, err := userDatabase(c, fallback)
+	if err != nil {
+		return ""
+	}
+	if database == "" {
+		return service.DefaultDatabase
+	}
+	return database
+}
+
+// userDatabase returns the database selected by the ?database= query
+// parameter or the X-Database header, falling back to the one named in the
+// legacy request body. Empty means the service default.
+func userDatabase(c *gin.Context, fallback string) (string, error) {
+	database := c.Query("database")
+	if database == "" {
+		database = c.GetHeader(DatabaseHeader)
+	}
+	if database == "" {
+		database = fallback
+	}
+
+	switch database {
+	case "", "mysql", "postgres":
+		return database, nil
+	default:
+		return "", invalidField("database", fmt.Sprintf("must be mysql or postgres, got %q", database))
 	}
 }
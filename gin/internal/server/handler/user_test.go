@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -13,6 +14,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/yizhinailong/demo/gin/internal/model"
+	"github.com/yizhinailong/demo/gin/internal/repository"
+	"github.com/yizhinailong/demo/gin/internal/server/dto"
 	"github.com/yizhinailong/demo/gin/internal/service"
 )
 
@@ -37,6 +40,27 @@ func (m *MockUserService) CreateUser(ctx context.Context, input *service.CreateU
 	return args.Get(0).(*model.User), args.Error(1)
 }
 
+func (m *MockUserService) UpdateUser(ctx context.Context, input *service.UpdateUserInput) (*model.User, error) {
+	args := m.Called(ctx, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
+func (m *MockUserService) DeleteUser(ctx context.Context, input *service.DeleteUserInput) error {
+	args := m.Called(ctx, input)
+	return args.Error(0)
+}
+
+func (m *MockUserService) ListUsers(ctx context.Context, input *service.ListUsersInput) (*service.UserList, error) {
+	args := m.Called(ctx, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.UserList), args.Error(1)
+}
+
 func setupTestRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
@@ -65,22 +89,25 @@ func TestUserHandler_CreateUser(t *testing.T) {
 			Email: "test@example.com",
 		}
 
-		mockService.On("CreateUser", mock.Anything, &input).Return(expectedUser, nil)
+		mockService.On("CreateUser", mock.Anything, &input).Return(expectedUser, nil).Once()
 
-		body, _ := json.Marshal(input)
-		req := httptest.NewRequest("POST", "/users/create", bytes.NewBuffer(body))
+		body, _ := json.Marshal(dto.CreateUserRequest{Name: input.Name, Email: input.Email})
+		req := httptest.NewRequest("POST", "/users?database=mysql", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
 
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
-		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "/users/1", w.Header().Get("Location"))
+		assert.Empty(t, w.Header().Get("Deprecation"))
 
-		var response map[string]interface{}
+		var response dto.Response[*model.User]
 		err := json.Unmarshal(w.Body.Bytes(), &response)
 		assert.NoError(t, err)
-		assert.Equal(t, "user created", response["status"])
-		assert.Equal(t, float64(1), response["userID"])
+		assert.Equal(t, "user successfully created", response.Message)
+		assert.Equal(t, int64(1), response.Data.ID)
+		assert.Empty(t, response.Code)
 
 		mockService.AssertExpectations(t)
 	})
@@ -91,23 +118,87 @@ func TestUserHandler_CreateUser(t *testing.T) {
 		}
 
 		body, _ := json.Marshal(input)
-		req := httptest.NewRequest("POST", "/users/create", bytes.NewBuffer(body))
+		req := httptest.NewRequest("POST", "/users", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
 
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
 		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response dto.Response[any]
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, dto.CodeValidationFailed, response.Code)
+		assert.Nil(t, response.Data)
+		assert.Contains(t, response.Errors, dto.FieldError{Field: "name", Message: "failed on the 'required' rule"})
+	})
+
+	t.Run("create user with duplicate email", func(t *testing.T) {
+		input := service.CreateUserInput{
+			Name:  "testuser",
+			Email: "taken@example.com",
+		}
+
+		mockService.On("CreateUser", mock.Anything, &input).
+			Return(nil, fmt.Errorf("创建用户失败: %w", repository.ErrDuplicate)).Once()
+
+		body, _ := json.Marshal(dto.CreateUserRequest{Name: input.Name, Email: input.Email})
+		req := httptest.NewRequest("POST", "/users", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), `"code":"conflict"`)
+		assert.NotContains(t, w.Body.String(), "taken@example.com")
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("create user with unknown database", func(t *testing.T) {
+		body, _ := json.Marshal(dto.CreateUserRequest{Name: "testuser", Email: "test@example.com"})
+		req := httptest.NewRequest("POST", "/users", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(DatabaseHeader, "oracle")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
 
 	t.Run("create user with service error", func(t *testing.T) {
+		input := service.CreateUserInput{
+			Name:     "testuser",
+			Email:    "test@example.com",
+			Database: "postgres",
+		}
+
+		mockService.On("CreateUser", mock.Anything, &input).Return(nil, assert.AnError).Once()
+
+		body, _ := json.Marshal(dto.CreateUserRequest{Name: input.Name, Email: input.Email})
+		req := httptest.NewRequest("POST", "/users", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(DatabaseHeader, "postgres")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.NotContains(t, w.Body.String(), assert.AnError.Error())
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("deprecated alias", func(t *testing.T) {
 		input := service.CreateUserInput{
 			Name:     "testuser",
 			Email:    "test@example.com",
 			Database: "mysql",
 		}
 
-		mockService.On("CreateUser", mock.Anything, &input).Return(nil, assert.AnError)
+		mockService.On("CreateUser", mock.Anything, &input).Return(&model.User{ID: 2}, nil).Once()
 
 		body, _ := json.Marshal(input)
 		req := httptest.NewRequest("POST", "/users/create", bytes.NewBuffer(body))
@@ -116,7 +207,9 @@ func TestUserHandler_CreateUser(t *testing.T) {
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "@1792281600", w.Header().Get("Deprecation"))
+		assert.Equal(t, `</users>; rel="successor-version"`, w.Header().Get("Link"))
 
 		mockService.AssertExpectations(t)
 	})
@@ -131,63 +224,92 @@ func TestUserHandler_GetUser(t *testing.T) {
 	router := setupTestRouter()
 	handler.RegisterRoutes(router)
 
+	expectedUser := &model.User{
+		ID:        1,
+		Name:      "testuser",
+		Email:     "test@example.com",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
 	t.Run("get user successfully", func(t *testing.T) {
 		input := service.GetUserInput{
 			ID:       1,
 			Database: "mysql",
 		}
 
-		expectedUser := &model.User{
-			ID:        1,
-			Name:      "testuser",
-			Email:     "test@example.com",
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		}
+		mockService.On("GetUser", mock.Anything, &input).Return(expectedUser, nil).Once()
 
-		mockService.On("GetUser", mock.Anything, &input).Return(expectedUser, nil)
-
-		body, _ := json.Marshal(input)
-		req := httptest.NewRequest("GET", "/users/get", bytes.NewBuffer(body))
-		req.Header.Set("Content-Type", "application/json")
+		req := httptest.NewRequest("GET", "/users/1?database=mysql", nil)
 
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
 		assert.Equal(t, http.StatusOK, w.Code)
 
-		var response model.User
+		var response dto.Response[*model.User]
 		err := json.Unmarshal(w.Body.Bytes(), &response)
 		assert.NoError(t, err)
-		assert.Equal(t, expectedUser.ID, response.ID)
-		assert.Equal(t, expectedUser.Name, response.Name)
-		assert.Equal(t, expectedUser.Email, response.Email)
+		assert.Equal(t, expectedUser.ID, response.Data.ID)
+		assert.Equal(t, expectedUser.Name, response.Data.Name)
+		assert.Equal(t, expectedUser.Email, response.Data.Email)
 
 		mockService.AssertExpectations(t)
 	})
 
-	t.Run("get user with invalid input", func(t *testing.T) {
-		input := map[string]string{
-			"invalid": "data",
-		}
-
-		body, _ := json.Marshal(input)
-		req := httptest.NewRequest("GET", "/users/get", bytes.NewBuffer(body))
-		req.Header.Set("Content-Type", "application/json")
+	t.Run("get user with invalid id", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/users/abc", nil)
 
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
 		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"field":"id"`)
+	})
+
+	t.Run("get unknown user", func(t *testing.T) {
+		input := service.GetUserInput{
+			ID: 404,
+		}
+
+		mockService.On("GetUser", mock.Anything, &input).
+			Return(nil, fmt.Errorf("查询用户失败: %w", repository.ErrNotFound)).Once()
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/users/404", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), `"code":"not_found"`)
+
+		mockService.AssertExpectations(t)
 	})
 
 	t.Run("get non-existent user", func(t *testing.T) {
 		input := service.GetUserInput{
 			ID:       999,
+			Database: "postgres",
+		}
+
+		mockService.On("GetUser", mock.Anything, &input).Return(nil, assert.AnError).Once()
+
+		req := httptest.NewRequest("GET", "/users/999", nil)
+		req.Header.Set(DatabaseHeader, "postgres")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("deprecated alias reads the JSON body", func(t *testing.T) {
+		input := service.GetUserInput{
+			ID:       1,
 			Database: "mysql",
 		}
 
-		mockService.On("GetUser", mock.Anything, &input).Return(nil, assert.AnError)
+		mockService.On("GetUser", mock.Anything, &input).Return(expectedUser, nil).Once()
 
 		body, _ := json.Marshal(input)
 		req := httptest.NewRequest("GET", "/users/get", bytes.NewBuffer(body))
@@ -196,10 +318,26 @@ func TestUserHandler_GetUser(t *testing.T) {
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Deprecation"))
 
 		mockService.AssertExpectations(t)
 	})
+
+	t.Run("deprecated alias with invalid input", func(t *testing.T) {
+		input := map[string]string{
+			"invalid": "data",
+		}
+
+		body, _ := json.Marshal(input)
+		req := httptest.NewRequest("GET", "/users/get", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
 }
 
 func TestUserHandler_RegisterRoutes(t *testing.T) {
@@ -214,17 +352,297 @@ func TestUserHandler_RegisterRoutes(t *testing.T) {
 	routes := router.Routes()
 	assert.NotEmpty(t, routes)
 
-	// Check for our specific routes
-	var foundCreate, foundGet bool
+	registered := map[string]bool{}
 	for _, route := range routes {
-		if route.Path == "/users/create" && route.Method == "POST" {
-			foundCreate = true
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	for _, route := range []string{
+		"GET /users", "POST /users", "GET /users/:id", "PUT /users/:id", "PATCH /users/:id", "DELETE /users/:id",
+		"POST /users/create", "GET /users/get",
+	} {
+		assert.True(t, registered[route], "%s should be registered", route)
+	}
+}
+
+func TestOwnerDatabase(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name     string
+		target   string
+		header   string
+		body     string
+		legacy   bool
+		database string
+	}{
+		{name: "default", target: "/users/1", database: service.DefaultDatabase},
+		{name: "query", target: "/users/1?database=postgres", database: "postgres"},
+		{name: "header", target: "/users/1", header: "postgres", database: "postgres"},
+		{name: "invalid selector matches nothing", target: "/users/1?database=oracle", database: ""},
+		{name: "legacy body", target: "/users/get", body: `{"id":1,"database":"postgres"}`, legacy: true, database: "postgres"},
+		{name: "legacy query wins over body", target: "/users/get?database=mysql", body: `{"id":1,"database":"postgres"}`, legacy: true, database: "mysql"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, tt.target, bytes.NewBufferString(tt.body))
+			if tt.header != "" {
+				c.Request.Header.Set("X-Database", tt.header)
+			}
+
+			resolve := ownerDatabase
+			if tt.legacy {
+				resolve = legacyOwnerDatabase
+			}
+			assert.Equal(t, tt.database, resolve(c))
+		})
+	}
+}
+
+func TestUserHandler_GetUser_Unavailable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "deadline exceeded mid-query", err: fmt.Errorf("查询用户失败: %w", context.DeadlineExceeded), code: http.StatusGatewayTimeout},
+		{name: "database unreachable", err: fmt.Errorf("%w for mysql: dial tcp", repository.ErrNotConnected), code: http.StatusServiceUnavailable},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockUserService)
+			handler := &UserHandler{userService: mockService}
+			router := setupTestRouter()
+			handler.RegisterRoutes(router)
+
+			input := service.GetUserInput{ID: int64(i + 1), Database: "mysql"}
+			mockService.On("GetUser", mock.Anything, &input).Return(nil, tt.err).Once()
+
+			req := httptest.NewRequest("GET", fmt.Sprintf("/users/%d?database=mysql", input.ID), nil)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.code, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestUserHandler_UpdateUser(t *testing.T) {
+	mockService := new(MockUserService)
+	handler := &UserHandler{
+		userService: mockService,
+	}
+
+	router := setupTestRouter()
+	handler.RegisterRoutes(router)
+
+	t.Run("replace user", func(t *testing.T) {
+		input := service.UpdateUserInput{
+			ID:    1,
+			Name:  strPtr("renamed"),
+			Email: strPtr("renamed@example.com"),
 		}
-		if route.Path == "/users/get" && route.Method == "GET" {
-			foundGet = true
+		updated := &model.User{ID: 1, Name: "renamed", Email: "renamed@example.com"}
+
+		mockService.On("UpdateUser", mock.Anything, &input).Return(updated, nil).Once()
+
+		body, _ := json.Marshal(dto.UpdateUserRequest{Name: "renamed", Email: "renamed@example.com"})
+		req := httptest.NewRequest("PUT", "/users/1", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response dto.Response[*model.User]
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "renamed", response.Data.Name)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("replace user with missing fields", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/users/1", bytes.NewBufferString(`{"name":"renamed"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("patch only the given fields", func(t *testing.T) {
+		input := service.UpdateUserInput{
+			ID:       1,
+			Role:     strPtr("operator"),
+			Database: "postgres",
+		}
+
+		mockService.On("UpdateUser", mock.Anything, &input).Return(&model.User{ID: 1, Role: model.RoleOperator}, nil).Once()
+
+		req := httptest.NewRequest("PATCH", "/users/1?database=postgres", bytes.NewBufferString(`{"role":"operator"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("role change by non-admin", func(t *testing.T) {
+		input := service.UpdateUserInput{
+			ID:   2,
+			Role: strPtr("admin"),
 		}
+
+		mockService.On("UpdateUser", mock.Anything, &input).Return(nil, service.ErrRoleChangeForbidden).Once()
+
+		req := httptest.NewRequest("PATCH", "/users/2", bytes.NewBufferString(`{"role":"admin"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("update with invalid id", func(t *testing.T) {
+		req := httptest.NewRequest("PATCH", "/users/abc", bytes.NewBufferString(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestUserHandler_DeleteUser(t *testing.T) {
+	mockService := new(MockUserService)
+	handler := &UserHandler{
+		userService: mockService,
 	}
 
-	assert.True(t, foundCreate, "Create user route should be registered")
-	assert.True(t, foundGet, "Get user route should be registered")
+	router := setupTestRouter()
+	handler.RegisterRoutes(router)
+
+	t.Run("delete user successfully", func(t *testing.T) {
+		mockService.On("DeleteUser", mock.Anything, &service.DeleteUserInput{ID: 1}).Return(nil).Once()
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("DELETE", "/users/1", nil))
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Body.String())
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("delete user with service error", func(t *testing.T) {
+		mockService.On("DeleteUser", mock.Anything, &service.DeleteUserInput{ID: 2}).Return(assert.AnError).Once()
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("DELETE", "/users/2", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestUserHandler_ListUsers(t *testing.T) {
+	mockService := new(MockUserService)
+	handler := &UserHandler{
+		userService: mockService,
+	}
+
+	router := setupTestRouter()
+	handler.RegisterRoutes(router)
+
+	t.Run("list users", func(t *testing.T) {
+		users := []*model.User{{ID: 1, Name: "alice"}, {ID: 2, Name: "bob"}}
+		mockService.On("ListUsers", mock.Anything, &service.ListUsersInput{Database: "mysql"}).
+			Return(&service.UserList{Users: users, NextCursor: "abc", Total: -1}, nil).Once()
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/users?database=mysql", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response dto.Response[dto.ListUsersResponse]
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Len(t, response.Data.Users, 2)
+		assert.Equal(t, "abc", response.Data.NextCursor)
+		assert.Nil(t, response.Data.Total)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("query parameters", func(t *testing.T) {
+		input := &service.ListUsersInput{
+			Limit:       10,
+			Cursor:      "abc",
+			Sort:        "-created_at",
+			NamePrefix:  "al",
+			EmailDomain: "example.com",
+			CreatedFrom: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			CreatedTo:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			CountTotal:  true,
+		}
+		mockService.On("ListUsers", mock.Anything, mock.MatchedBy(func(got *service.ListUsersInput) bool {
+			return got.CreatedFrom.Equal(input.CreatedFrom) && got.CreatedTo.Equal(input.CreatedTo) &&
+				got.Limit == input.Limit && got.Cursor == input.Cursor && got.Sort == input.Sort &&
+				got.NamePrefix == input.NamePrefix && got.EmailDomain == input.EmailDomain && got.CountTotal
+		})).Return(&service.UserList{Total: 0}, nil).Once()
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/users?limit=10&cursor=abc&sort=-created_at&name_prefix=al"+
+			"&email_domain=example.com&created_from=2025-01-01T00:00:00Z&created_to=2026-01-01T00:00:00Z&total=true", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"users":[]`)
+		assert.Contains(t, w.Body.String(), `"total":0`)
+		assert.NotContains(t, w.Body.String(), `next_cursor`)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("malformed query parameter", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/users?created_from=yesterday", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("invalid list options", func(t *testing.T) {
+		input := &service.ListUsersInput{Sort: "email"}
+		mockService.On("ListUsers", mock.Anything, input).
+			Return(nil, &service.ValidationError{Fields: []service.FieldError{
+				{Field: "sort", Message: "must be one of id, name, created_at"},
+			}}).Once()
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/users?sort=email", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"code":"validation_failed"`)
+		assert.Contains(t, w.Body.String(), `"field":"sort"`)
+
+		mockService.AssertExpectations(t)
+	})
 }
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/yizhinailong/demo/gin/internal/config"
+	"github.com/yizhinailong/demo/gin/pkg/logger"
+)
+
+// setConfig publishes a copy of the default config changed by mutate
+func setConfig(t *testing.T, mutate func(cfg *config.Config)) {
+	t.Helper()
+	prev := config.GetConfig()
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	mutate(cfg)
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(prev) })
+}
+
+// observeLogs points logger.L at an in-memory core
+func observeLogs(t *testing.T) *observer.ObservedLogs {
+	t.Helper()
+	core, logs := observer.New(zapcore.DebugLevel)
+	prev := logger.L
+	logger.L = zap.New(core)
+	t.Cleanup(func() { logger.L = prev })
+	return logs
+}
+
+func TestAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setConfig(t, func(cfg *config.Config) {
+		cfg.Log.Access = config.AccessLogConfig{
+			Skip:          []string{"/metrics"},
+			Sample:        []config.RouteSample{{Route: "/healthz", Every: 3}},
+			SlowThreshold: "50ms",
+		}
+	})
+	logs := observeLogs(t)
+
+	status := http.StatusOK
+	r := gin.New()
+	r.Use(RequestID(), AccessLog())
+	r.GET("/healthz", func(c *gin.Context) { c.Status(status) })
+	r.GET("/metrics", func(c *gin.Context) { c.Status(status) })
+	r.GET("/slow", func(c *gin.Context) {
+		time.Sleep(60 * time.Millisecond)
+		c.Status(http.StatusOK)
+	})
+
+	get := func(path string) {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	t.Run("samples successful requests", func(t *testing.T) {
+		for range 7 {
+			get("/healthz")
+		}
+		// Hits 1, 4 and 7
+		assert.Len(t, logs.TakeAll(), 3)
+	})
+
+	t.Run("skips successful requests", func(t *testing.T) {
+		get("/metrics")
+		assert.Empty(t, logs.TakeAll())
+	})
+
+	t.Run("always logs errors", func(t *testing.T) {
+		status = http.StatusServiceUnavailable
+		defer func() { status = http.StatusOK }()
+
+		for range 3 {
+			get("/healthz")
+			get("/metrics")
+		}
+		entries := logs.TakeAll()
+		assert.Len(t, entries, 6)
+		assert.Equal(t, "access", entries[0].LoggerName)
+		assert.NotEmpty(t, entries[0].ContextMap()["request_id"])
+	})
+
+	t.Run("warns on slow requests", func(t *testing.T) {
+		get("/slow")
+
+		slow := logs.FilterMessage("Slow request").TakeAll()
+		require.Len(t, slow, 1)
+		assert.Equal(t, zapcore.WarnLevel, slow[0].Level)
+		assert.Equal(t, "/slow", slow[0].ContextMap()["route"])
+	})
+}
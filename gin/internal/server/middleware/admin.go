@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yizhinailong/demo/gin/internal/config"
+)
+
+// AdminTokenHeader carries the admin token, kept apart from Authorization so
+// it never collides with end-user credentials.
+const AdminTokenHeader = "X-Admin-Token"
+
+// AdminAuth guards the admin endpoints with config.AdminConfig.Token. While no
+// token is configured the endpoints answer like an unknown route.
+func AdminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := config.GetConfig().Admin.Token
+		if token == "" {
+			NotFound(c)
+			return
+		}
+
+		got := c.GetHeader(AdminTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			deny(c, http.StatusUnauthorized, ReasonInvalidCredentials, "invalid admin token")
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yizhinailong/demo/gin/internal/auth"
+	"github.com/yizhinailong/demo/gin/internal/config"
+
+	router "github.com/yizhinailong/demo/gin/internal/server"
+)
+
+// fakeKeys accepts the keys it maps to scopes and fails lookups for "down"
+type fakeKeys map[string][]string
+
+func (f fakeKeys) AuthenticateKey(_ context.Context, key string) (*auth.Principal, error) {
+	if key == "down" {
+		return nil, assert.AnError
+	}
+	scopes, ok := f[key]
+	if !ok {
+		return nil, auth.ErrInvalidAPIKey
+	}
+	return &auth.Principal{Subject: "api_key:" + key, Scopes: scopes}, nil
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setConfig(t, func(cfg *config.Config) {
+		// No JWT settings: API keys alone protect the routes
+		cfg.Auth = config.AuthConfig{Enabled: true}
+	})
+
+	r := gin.New()
+	r.Use(APIKeyAuth(fakeKeys{"reader": {"items:read"}, "writer": {"items:write"}}), Authenticate())
+	r.POST("/items", func(c *gin.Context) {
+		p, _ := auth.GetPrincipal(c)
+		c.String(http.StatusOK, p.Subject)
+	})
+	router.AddRules(r, router.Rule{Method: http.MethodPost, Path: "/items", Scopes: []string{"items:write"}})
+
+	do := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/items", nil)
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name string
+		key  string
+		code int
+		body string
+	}{
+		{name: "missing key", key: "", code: http.StatusUnauthorized},
+		{name: "unknown key", key: "nope", code: http.StatusUnauthorized, body: `{"code":"invalid_credentials","message":"invalid API key"}`},
+		{name: "lookup failure", key: "down", code: http.StatusServiceUnavailable, body: `{"code":"unavailable","message":"authentication temporarily unavailable"}`},
+		{name: "insufficient scope", key: "reader", code: http.StatusForbidden},
+		{name: "granted scope", key: "writer", code: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(tt.key)
+			assert.Equal(t, tt.code, w.Code)
+			if tt.body != "" {
+				assert.JSONEq(t, tt.body, w.Body.String())
+			}
+		})
+	}
+
+	assert.Equal(t, "api_key:writer", do("writer").Body.String())
+}
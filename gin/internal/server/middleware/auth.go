@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/yizhinailong/demo/gin/internal/auth"
+	"github.com/yizhinailong/demo/gin/internal/config"
+	"github.com/yizhinailong/demo/gin/pkg/logger"
+
+	router "github.com/yizhinailong/demo/gin/internal/server"
+)
+
+// Authenticate verifies bearer JWTs and enforces the scopes declared through
+// router.RuleProvider. A valid token on any route sets the principal and an
+// invalid one is always rejected, while a missing token is only rejected on
+// routes that have a rule. The verifier is rebuilt when auth settings change
+// on reload.
+func Authenticate() gin.HandlerFunc {
+	var verifier atomic.Pointer[auth.Verifier]
+	build := func(cfg config.AuthConfig) {
+		if !cfg.Enabled || !cfg.JWT.Configured() {
+			verifier.Store(nil)
+			return
+		}
+		v, err := auth.NewVerifier(cfg.JWT)
+		if err != nil {
+			// Keep the previous keys rather than locking everyone out
+			slog.Error("Failed to load JWT keys", "error", err)
+			return
+		}
+		verifier.Store(v)
+	}
+	build(config.GetConfig().Auth)
+
+	config.Subscribe(func(old, new *config.Config) {
+		if old == nil || !reflect.DeepEqual(old.Auth, new.Auth) {
+			build(new.Auth)
+		}
+	})
+
+	return func(c *gin.Context) {
+		if !config.GetConfig().Auth.Enabled {
+			c.Next()
+			return
+		}
+
+		rule, protected := router.RuleFor(c.Request.Method, c.FullPath())
+
+		// Already authenticated by APIKeyAuth
+		if principal, ok := auth.GetPrincipal(c); ok {
+			if protected && !principal.HasScopes(rule.Scopes...) {
+				forbidden(c, rule)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		token, hasToken := bearerToken(c)
+		if !hasToken {
+			if protected {
+				unauthorized(c, ReasonUnauthenticated, "missing bearer token")
+				return
+			}
+			c.Next()
+			return
+		}
+
+		v := verifier.Load()
+		if v == nil {
+			unauthorized(c, ReasonInvalidCredentials, "bearer tokens are not accepted")
+			return
+		}
+		principal, err := v.Verify(token)
+		if err != nil {
+			logger.FromContext(c.Request.Context()).Info("Rejected bearer token", zap.Error(err))
+			unauthorized(c, ReasonInvalidCredentials, "invalid bearer token")
+			return
+		}
+
+		auth.SetPrincipal(c, principal)
+		c.Request = c.Request.WithContext(logger.WithFields(c.Request.Context(), zap.String("subject", principal.Subject)))
+
+		if protected && !principal.HasScopes(rule.Scopes...) {
+			forbidden(c, rule)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func bearerToken(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+		return strings.TrimSpace(header[7:]), true
+	}
+	return "", false
+}
+
+func forbidden(c *gin.Context, rule router.Rule) {
+	c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+strings.Join(rule.Scopes, " ")+`"`)
+	deny(c, http.StatusForbidden, ReasonInsufficientScope, "insufficient scope")
+}
+
+// unauthorized rejects a missing credential, or an invalid one when reason
+// is ReasonInvalidCredentials
+func unauthorized(c *gin.Context, reason, message string) {
+	c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	deny(c, http.StatusUnauthorized, reason, message)
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yizhinailong/demo/gin/internal/auth"
+	"github.com/yizhinailong/demo/gin/internal/config"
+
+	router "github.com/yizhinailong/demo/gin/internal/server"
+)
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+func testToken(t *testing.T, scope string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   "user-1",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": scope,
+	}).SignedString([]byte(testJWTSecret))
+	require.NoError(t, err)
+	return token
+}
+
+func TestAuthenticate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setConfig(t, func(cfg *config.Config) {
+		cfg.Auth = config.AuthConfig{
+			Enabled: true,
+			JWT:     config.JWTConfig{HS256Secret: testJWTSecret},
+		}
+	})
+
+	r := gin.New()
+	r.Use(Authenticate())
+	handler := func(c *gin.Context) {
+		p, ok := auth.GetPrincipal(c)
+		fromCtx, _ := auth.FromContext(c.Request.Context())
+		assert.Same(t, p, fromCtx)
+		if ok {
+			c.String(http.StatusOK, p.Subject)
+			return
+		}
+		c.String(http.StatusOK, "anonymous")
+	}
+	r.GET("/open", handler)
+	r.POST("/items", handler)
+	router.AddRules(r, router.Rule{Method: http.MethodPost, Path: "/items", Scopes: []string{"items:write"}})
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("anonymous on open route", func(t *testing.T) {
+		w := do(http.MethodGet, "/open", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "anonymous", w.Body.String())
+	})
+
+	t.Run("principal on open route", func(t *testing.T) {
+		w := do(http.MethodGet, "/open", testToken(t, ""))
+		assert.Equal(t, "user-1", w.Body.String())
+	})
+
+	t.Run("missing token on protected route", func(t *testing.T) {
+		w := do(http.MethodPost, "/items", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), "invalid_token")
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		w := do(http.MethodGet, "/open", "garbage")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.JSONEq(t, `{"code":"invalid_credentials","message":"invalid bearer token"}`, w.Body.String())
+	})
+
+	t.Run("insufficient scope", func(t *testing.T) {
+		w := do(http.MethodPost, "/items", testToken(t, "items:read"))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), `scope="items:write"`)
+	})
+
+	t.Run("granted scope", func(t *testing.T) {
+		w := do(http.MethodPost, "/items", testToken(t, "items:read items:write"))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "user-1", w.Body.String())
+	})
+
+	t.Run("disabled auth serves protected routes anonymously", func(t *testing.T) {
+		next := *config.GetConfig()
+		next.Auth.Enabled = false
+		config.Set(&next)
+
+		w := do(http.MethodPost, "/items", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "anonymous", w.Body.String())
+	})
+}
+
+func TestAddRules_UnknownRoute(t *testing.T) {
+	r := gin.New()
+	assert.Panics(t, func() {
+		router.AddRules(r, router.Rule{Method: http.MethodGet, Path: "/missing"})
+	})
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yizhinailong/demo/gin/internal/auth"
+	"github.com/yizhinailong/demo/gin/internal/config"
+	"github.com/yizhinailong/demo/gin/internal/model"
+
+	router "github.com/yizhinailong/demo/gin/internal/server"
+)
+
+// fakeRoles maps subjects to roles; the subject "down" fails the lookup
+type fakeRoles map[string]model.Role
+
+func (f fakeRoles) RoleOf(_ context.Context, p *auth.Principal) (model.Role, error) {
+	if p.Subject == "down" {
+		return "", assert.AnError
+	}
+	return f[p.Subject], nil
+}
+
+func TestAuthorize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setConfig(t, func(cfg *config.Config) {
+		cfg.Auth = config.AuthConfig{Enabled: true, Users: config.UsersConfig{Database: "mysql"}}
+	})
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if subject := c.GetHeader("X-Subject"); subject != "" {
+			auth.SetPrincipal(c, &auth.Principal{Subject: subject, APIKey: strings.HasPrefix(subject, "api_key:")})
+		}
+	})
+	r.Use(Authorize(fakeRoles{"1": model.RoleAdmin, "2": model.RoleOperator, "3": model.RoleViewer}))
+
+	ok := func(c *gin.Context) {
+		body, _ := c.GetRawData()
+		c.String(http.StatusOK, string(body))
+	}
+	r.POST("/users", ok)
+	r.GET("/users/:id", ok)
+	r.POST("/users/get", ok)
+	r.GET("/open", ok)
+	router.AddRules(r,
+		router.Rule{Method: http.MethodPost, Path: "/users", Roles: []model.Role{model.RoleAdmin}},
+		router.Rule{
+			Method: http.MethodGet, Path: "/users/:id", Roles: []model.Role{model.RoleAdmin, model.RoleOperator},
+			Owner: router.Param("id"), Database: func(c *gin.Context) string { return c.DefaultQuery("database", "mysql") },
+		},
+		router.Rule{Method: http.MethodPost, Path: "/users/get", Owner: router.JSONField("id")},
+	)
+
+	do := func(method, path, subject, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if subject != "" {
+			req.Header.Set("X-Subject", subject)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		subject string
+		body    string
+		code    int
+		reason  string
+	}{
+		{name: "no rule", method: http.MethodGet, path: "/open", code: http.StatusOK},
+		{name: "anonymous", method: http.MethodPost, path: "/users", code: http.StatusUnauthorized, reason: ReasonUnauthenticated},
+		{name: "admin creates", method: http.MethodPost, path: "/users", subject: "1", code: http.StatusOK},
+		{name: "operator cannot create", method: http.MethodPost, path: "/users", subject: "2", code: http.StatusForbidden, reason: ReasonInsufficientRole},
+		{name: "user without role", method: http.MethodPost, path: "/users", subject: "9", code: http.StatusForbidden, reason: ReasonInsufficientRole},
+		{name: "api key passes on scopes", method: http.MethodPost, path: "/users", subject: "api_key:9", code: http.StatusOK},
+		{name: "operator reads any user", method: http.MethodGet, path: "/users/3", subject: "2", code: http.StatusOK},
+		{name: "viewer reads self", method: http.MethodGet, path: "/users/3", subject: "3", code: http.StatusOK},
+		{name: "viewer reads same id in another database", method: http.MethodGet, path: "/users/3?database=postgres", subject: "3", code: http.StatusForbidden, reason: ReasonInsufficientRole},
+		{name: "viewer reads other", method: http.MethodGet, path: "/users/1", subject: "3", code: http.StatusForbidden, reason: ReasonInsufficientRole},
+		{name: "owner from body", method: http.MethodPost, path: "/users/get", subject: "3", body: `{"id":3}`, code: http.StatusOK},
+		{name: "other owner from body", method: http.MethodPost, path: "/users/get", subject: "3", body: `{"id":1}`, code: http.StatusForbidden, reason: ReasonInsufficientRole},
+		{name: "lookup failure", method: http.MethodPost, path: "/users", subject: "down", code: http.StatusServiceUnavailable, reason: ReasonUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(tt.method, tt.path, tt.subject, tt.body)
+			assert.Equal(t, tt.code, w.Code)
+			if tt.reason != "" {
+				assert.Contains(t, w.Body.String(), `"code":"`+tt.reason+`"`)
+			}
+		})
+	}
+
+	t.Run("body is still readable after owner check", func(t *testing.T) {
+		w := do(http.MethodPost, "/users/get", "3", `{"id":3}`)
+		assert.Equal(t, `{"id":3}`, w.Body.String())
+	})
+
+	t.Run("denial explains the policy", func(t *testing.T) {
+		w := do(http.MethodGet, "/users/1", "3", "")
+		assert.JSONEq(t, `{"code":"insufficient_role","message":"requires role admin or operator, or ownership of the resource"}`, w.Body.String())
+	})
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+
+	"github.com/yizhinailong/demo/gin/internal/config"
+)
+
+// corsPolicy applies one CORS configuration to requests under prefix
+type corsPolicy struct {
+	prefix  string
+	handler gin.HandlerFunc
+}
+
+// CORS applies the policy from config.CORSConfig, choosing the longest
+// matching cors.groups prefix for each request. Policies are rebuilt when a
+// config reload changes the cors section, so origin lists can be edited
+// without a restart.
+func CORS() gin.HandlerFunc {
+	var policies atomic.Pointer[[]corsPolicy]
+	policies.Store(buildCORS(config.GetConfig().CORS))
+
+	config.Subscribe(func(old, new *config.Config) {
+		if old != nil && reflect.DeepEqual(old.CORS, new.CORS) {
+			return
+		}
+		policies.Store(buildCORS(new.CORS))
+		slog.Info("CORS policy reloaded", "allow_origins", new.CORS.AllowOrigins)
+	})
+
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		for _, p := range *policies.Load() {
+			if matchPrefix(path, p.prefix) {
+				p.handler(c)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// buildCORS returns the group policies ordered by prefix length, longest
+// first, followed by the default policy
+func buildCORS(cfg config.CORSConfig) *[]corsPolicy {
+	policies := make([]corsPolicy, 0, len(cfg.Groups)+1)
+	for _, g := range cfg.Groups {
+		policies = append(policies, corsPolicy{prefix: g.Prefix, handler: corsHandler(cfg.Policy(g))})
+	}
+	slices.SortStableFunc(policies, func(a, b corsPolicy) int {
+		return len(b.prefix) - len(a.prefix)
+	})
+	policies = append(policies, corsPolicy{prefix: "/", handler: corsHandler(cfg.Policy(config.CORSGroup{}))})
+	return &policies
+}
+
+// corsHandler builds the gin-contrib/cors middleware for p. Without origins
+// no CORS headers are sent and browsers block cross-origin requests.
+func corsHandler(p config.CORSConfig) gin.HandlerFunc {
+	if len(p.AllowOrigins) == 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	cfg := cors.Config{
+		AllowOrigins:     p.AllowOrigins,
+		AllowMethods:     p.AllowMethods,
+		AllowHeaders:     p.AllowHeaders,
+		ExposeHeaders:    p.ExposeHeaders,
+		AllowCredentials: p.AllowCredentials,
+		MaxAge:           config.ParseDuration(p.MaxAge, 12*time.Hour),
+		AllowWildcard:    true,
+	}
+	// "*" anywhere in the list allows every origin
+	if slices.Contains(p.AllowOrigins, "*") {
+		cfg.AllowAllOrigins, cfg.AllowOrigins = true, nil
+	}
+	return cors.New(cfg)
+}
+
+func matchPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
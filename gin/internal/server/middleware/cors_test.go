@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yizhinailong/demo/gin/internal/config"
+)
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	noCredentials := false
+	setConfig(t, func(cfg *config.Config) {
+		cfg.CORS.AllowOrigins = []string{"https://app.example.com", "https://*.preview.example.com"}
+		cfg.CORS.AllowCredentials = true
+		cfg.CORS.Groups = []config.CORSGroup{
+			{Prefix: "/admin", AllowOrigins: []string{}},
+			{Prefix: "/public", AllowOrigins: []string{"*"}, AllowCredentials: &noCredentials},
+		}
+	})
+
+	r := gin.New()
+	r.Use(CORS())
+	for _, path := range []string{"/users/get", "/admin/config", "/public/info"} {
+		r.GET(path, func(c *gin.Context) { c.Status(http.StatusOK) })
+	}
+
+	request := func(method, path, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Origin", origin)
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("allowed origin with credentials", func(t *testing.T) {
+		w := request(http.MethodGet, "/users/get", "https://app.example.com")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "X-Request-Id", w.Header().Get("Access-Control-Expose-Headers"))
+	})
+
+	t.Run("wildcard origin", func(t *testing.T) {
+		w := request(http.MethodOptions, "/users/get", "https://pr-7.preview.example.com")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "https://pr-7.preview.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "43200", w.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("unknown origin is rejected", func(t *testing.T) {
+		w := request(http.MethodGet, "/users/get", "https://evil.example.net")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("group disables cors", func(t *testing.T) {
+		w := request(http.MethodGet, "/admin/config", "https://app.example.com")
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("group allows any origin", func(t *testing.T) {
+		w := request(http.MethodGet, "/public/info", "https://anyone.example.org")
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("origins follow config reloads", func(t *testing.T) {
+		next := *config.GetConfig()
+		next.CORS.AllowOrigins = []string{"https://new.example.com"}
+		config.Set(&next)
+
+		assert.Equal(t, http.StatusForbidden, request(http.MethodGet, "/users/get", "https://app.example.com").Code)
+		w := request(http.MethodGet, "/users/get", "https://new.example.com")
+		assert.Equal(t, "https://new.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
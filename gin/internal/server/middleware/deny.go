@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/yizhinailong/demo/gin/internal/server/dto"
+)
+
+// Error codes of denied requests, so clients can tell a missing credential
+// from a missing permission
+const (
+	ReasonUnauthenticated    = "unauthenticated"
+	ReasonInvalidCredentials = "invalid_credentials"
+	ReasonInsufficientScope  = "insufficient_scope"
+	ReasonInsufficientRole   = "insufficient_role"
+	ReasonUnavailable        = "unavailable"
+)
+
+// deny aborts an authentication or authorization failure
+func deny(c *gin.Context, status int, reason, message string) {
+	Abort(c, dto.Error{Status: status, Code: reason, Message: message})
+}
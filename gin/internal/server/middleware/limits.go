@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yizhinailong/demo/gin/internal/config"
+	"github.com/yizhinailong/demo/gin/internal/server/dto"
+)
+
+// BodyLimit rejects request bodies larger than the route's max_body_bytes
+// with 413. Bodies without a Content-Length are read up to the limit before
+// the handler runs, so an overflow never reaches a half-decoded handler as a
+// 400.
+func BodyLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, _ := config.GetConfig().Server.Limits(c.Request.Method, c.FullPath())
+		if limit <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			tooLarge(c, limit)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, limit+1))
+		if err != nil {
+			Abort(c, dto.Error{Status: http.StatusBadRequest, Code: dto.CodeInvalidRequest, Message: "failed to read request body"})
+			return
+		}
+		if int64(len(body)) > limit {
+			tooLarge(c, limit)
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+func tooLarge(c *gin.Context, limit int64) {
+	// The rest of the body is not drained; close the connection instead
+	c.Header("Connection", "close")
+	Abort(c, dto.Error{
+		Status:  http.StatusRequestEntityTooLarge,
+		Code:    dto.CodePayloadTooLarge,
+		Message: "request body exceeds " + strconv.FormatInt(limit, 10) + " bytes",
+	})
+}
+
+// Timeout puts the route's request_timeout on the request context. Handlers
+// pass that context to services, so a slow query is cancelled and the handler
+// answers 504. If a handler returns without writing anything after the
+// deadline passed, Timeout answers 504 itself.
+func Timeout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, timeout := config.GetConfig().Server.Limits(c.Request.Method, c.FullPath())
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if !c.Writer.Written() && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			Abort(c, dto.Error{Status: http.StatusGatewayTimeout, Code: dto.CodeTimeout, Message: "request timed out"})
+		}
+	}
+}
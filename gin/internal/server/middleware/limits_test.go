@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yizhinailong/demo/gin/internal/config"
+)
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setConfig(t, func(cfg *config.Config) {
+		cfg.Server.MaxBodyBytes = 8
+		cfg.Server.Routes = []config.RouteLimits{
+			{Method: "post", Route: "/upload", MaxBodyBytes: 16},
+		}
+	})
+
+	r := gin.New()
+	r.Use(BodyLimit())
+	echo := func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		require.NoError(t, err)
+		c.String(http.StatusOK, string(body))
+	}
+	r.POST("/items", echo)
+	r.POST("/upload", echo)
+
+	do := func(path, body string, chunked bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if chunked {
+			// Hide the length, as with Transfer-Encoding: chunked
+			req.ContentLength = -1
+			req.Body = io.NopCloser(strings.NewReader(body))
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("within limit", func(t *testing.T) {
+		w := do("/items", "12345678", false)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "12345678", w.Body.String())
+	})
+
+	t.Run("declared length over limit", func(t *testing.T) {
+		w := do("/items", "123456789", false)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assert.JSONEq(t, `{"code":"payload_too_large","message":"request body exceeds 8 bytes"}`, w.Body.String())
+	})
+
+	t.Run("chunked body over limit", func(t *testing.T) {
+		w := do("/items", "123456789", true)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("chunked body within limit", func(t *testing.T) {
+		w := do("/items", "1234", true)
+		assert.Equal(t, "1234", w.Body.String())
+	})
+
+	t.Run("route override", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do("/upload", strings.Repeat("x", 16), false).Code)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, do("/upload", strings.Repeat("x", 17), true).Code)
+	})
+}
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setConfig(t, func(cfg *config.Config) {
+		cfg.Server.RequestTimeout = "1h"
+		cfg.Server.Routes = []config.RouteLimits{
+			{Method: http.MethodGet, Route: "/slow", RequestTimeout: "10ms"},
+		}
+	})
+
+	r := gin.New()
+	r.Use(Timeout())
+	r.GET("/deadline", func(c *gin.Context) {
+		deadline, ok := c.Request.Context().Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), deadline, time.Minute)
+		c.Status(http.StatusNoContent)
+	})
+	r.GET("/slow", func(c *gin.Context) {
+		// A handler that gives up without answering once the query is cancelled
+		<-c.Request.Context().Done()
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/deadline", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.JSONEq(t, `{"code":"timeout","message":"request timed out"}`, w.Body.String())
+}
@@ -1,19 +1,29 @@
 package middleware
 
 import (
-	"time"
-
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"go.uber.org/zap"
 
 	ginzap "github.com/gin-contrib/zap"
+
+	"github.com/yizhinailong/demo/gin/internal/service"
+	"github.com/yizhinailong/demo/gin/pkg/logger"
+	"github.com/yizhinailong/demo/gin/pkg/ratelimit"
 )
 
 func Use(r *gin.Engine) {
-	r.Use(cors.Default())
+	r.Use(RequestID())
+	r.Use(CORS())
+
+	r.Use(AccessLog())
+	r.Use(ginzap.CustomRecoveryWithZap(logger.L, true, recovered))
+	r.Use(BodyLimit())
+	r.Use(Timeout())
+	limits := ratelimit.NewMemoryStore()
+	r.Use(RateLimit(limits))
+	r.Use(APIKeyAuth(service.NewAPIKeyService()))
+	r.Use(Authenticate())
+	r.Use(PrincipalRateLimit(limits))
+	r.Use(Authorize(service.DefaultUserService()))
 
-	logger, _ := zap.NewProduction()
-	r.Use(ginzap.Ginzap(logger, time.RFC3339, true))
-	r.Use(ginzap.RecoveryWithZap(logger, true))
+	r.NoRoute(NotFound)
 }
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yizhinailong/demo/gin/internal/auth"
+	"github.com/yizhinailong/demo/gin/internal/config"
+	"github.com/yizhinailong/demo/gin/pkg/ratelimit"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, assert.AnError
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setConfig(t, func(cfg *config.Config) {
+		cfg.RateLimit = config.RateLimitConfig{
+			Enabled:  true,
+			Requests: 60,
+			Period:   "1m",
+			Burst:    3,
+			Routes: []config.RouteRateLimit{
+				{Method: "POST", Route: "/users/create", Requests: 1, Period: "1m", Burst: 1},
+			},
+		}
+	})
+
+	newRouter := func(store ratelimit.Store) *gin.Engine {
+		r := gin.New()
+		r.Use(RateLimit(store))
+		// Stands in for APIKeyAuth/Authenticate
+		r.Use(func(c *gin.Context) {
+			if subject := c.GetHeader("X-Test-Subject"); subject != "" {
+				auth.SetPrincipal(c, &auth.Principal{Subject: subject})
+			}
+		})
+		r.Use(PrincipalRateLimit(store))
+		r.GET("/hello", func(c *gin.Context) { c.Status(http.StatusOK) })
+		r.POST("/users/create", func(c *gin.Context) { c.Status(http.StatusOK) })
+		return r
+	}
+	r := newRouter(ratelimit.NewMemoryStore())
+
+	do := func(method, path, ip string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("per ip burst then 429", func(t *testing.T) {
+		for i := range 3 {
+			w := do(http.MethodGet, "/hello", "10.0.0.1")
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "3", w.Header().Get("RateLimit-Limit"))
+			assert.Equal(t, []string{"2", "1", "0"}[i], w.Header().Get("RateLimit-Remaining"))
+		}
+
+		w := do(http.MethodGet, "/hello", "10.0.0.1")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "1", w.Header().Get("Retry-After"))
+		assert.Equal(t, "3", w.Header().Get("RateLimit-Reset"))
+		assert.JSONEq(t, `{"code":"rate_limited","message":"rate limit exceeded"}`, w.Body.String())
+
+		// Another client is unaffected
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/hello", "10.0.0.2").Code)
+	})
+
+	t.Run("unverified credentials do not buy a fresh bucket", func(t *testing.T) {
+		for i := range 3 {
+			w := do(http.MethodGet, "/hello", "10.0.0.4", APIKeyHeader, "random-"+strconv.Itoa(i))
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, do(http.MethodGet, "/hello", "10.0.0.4", APIKeyHeader, "random-3").Code)
+		assert.Equal(t, http.StatusTooManyRequests, do(http.MethodGet, "/hello", "10.0.0.4", "Authorization", "Bearer random-4").Code)
+	})
+
+	t.Run("authenticated subject is limited across IPs", func(t *testing.T) {
+		for i := range 3 {
+			w := do(http.MethodGet, "/hello", "10.1.0."+strconv.Itoa(i), "X-Test-Subject", "api_key:7")
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+
+		w := do(http.MethodGet, "/hello", "10.1.0.9", "X-Test-Subject", "api_key:7")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+		// The fresh IP bucket of that request was still charged
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/hello", "10.1.0.9", "X-Test-Subject", "api_key:8").Code)
+	})
+
+	t.Run("headers describe the emptier bucket", func(t *testing.T) {
+		w := do(http.MethodGet, "/hello", "10.2.0.1", "X-Test-Subject", "user:1")
+		assert.Equal(t, "2", w.Header().Get("RateLimit-Remaining"))
+		do(http.MethodGet, "/hello", "10.2.0.2", "X-Test-Subject", "user:1")
+
+		w = do(http.MethodGet, "/hello", "10.2.0.3", "X-Test-Subject", "user:1")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	})
+
+	t.Run("route specific limit", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do(http.MethodPost, "/users/create", "10.0.0.3").Code)
+		w := do(http.MethodPost, "/users/create", "10.0.0.3")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "60", w.Header().Get("Retry-After"))
+		// The default bucket is separate
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/hello", "10.0.0.3").Code)
+	})
+
+	t.Run("store errors fail open", func(t *testing.T) {
+		r = newRouter(failingStore{})
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/hello", "10.0.0.1").Code)
+	})
+}
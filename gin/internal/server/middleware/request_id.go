@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/yizhinailong/demo/gin/pkg/logger"
+)
+
+// RequestIDHeader is read from the request and echoed on the response
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength caps client supplied IDs so they cannot bloat log lines
+const maxRequestIDLength = 128
+
+// RequestID accepts a well-formed X-Request-ID from the client or generates
+// one, stores it together with the matched route in the request context and
+// returns it to the caller. Services and repositories receive the ID through
+// c.Request.Context() and log it via logger.FromContext.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		ctx := logger.WithRequestID(c.Request.Context(), id)
+		if route := c.FullPath(); route != "" {
+			ctx = logger.WithFields(ctx, zap.String("route", route))
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(RequestIDHeader, id)
+
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID allows the characters used by common ID formats (UUIDs,
+// trace IDs, ULIDs) and rejects anything that could forge log output.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yizhinailong/demo/gin/internal/config"
+	"github.com/yizhinailong/demo/gin/internal/server/dto"
+	"github.com/yizhinailong/demo/gin/pkg/logger"
+)
+
+// ProblemContentType is sent with error bodies when server.error_format is
+// "problem"
+const ProblemContentType = "application/problem+json"
+
+// Respond writes data in the response envelope; message may be empty
+func Respond[T any](c *gin.Context, status int, message string, data T) {
+	c.JSON(status, dto.Response[T]{
+		Data:      data,
+		Message:   message,
+		RequestID: logger.RequestID(c.Request.Context()),
+	})
+}
+
+// Abort ends the request with e, as a dto.Response or, when
+// server.error_format is "problem", as a dto.Problem
+func Abort(c *gin.Context, e dto.Error) {
+	requestID := logger.RequestID(c.Request.Context())
+
+	settings := config.GetConfig().Server
+	if settings.ErrorFormat != "problem" {
+		c.AbortWithStatusJSON(e.Status, dto.Response[any]{
+			Code:      e.Code,
+			Message:   e.Message,
+			Errors:    e.Errors,
+			RequestID: requestID,
+		})
+		return
+	}
+
+	// render.JSON keeps a Content-Type that is already set
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(e.Status, dto.NewProblem(e, settings.ProblemTypeBase, c.Request.URL.Path, requestID))
+}
+
+// NotFound answers unknown routes, and routes that pretend not to exist, with
+// the regular error body
+func NotFound(c *gin.Context) {
+	Abort(c, dto.Error{Status: http.StatusNotFound, Code: dto.CodeNotFound, Message: "resource not found"})
+}
+
+// recovered answers requests whose handler panicked; the panic itself is
+// logged by the recovery middleware
+func recovered(c *gin.Context, _ any) {
+	Abort(c, dto.Error{Status: http.StatusInternalServerError, Code: dto.CodeInternal, Message: "internal server error"})
+}
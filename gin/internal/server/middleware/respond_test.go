@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yizhinailong/demo/gin/internal/config"
+	"github.com/yizhinailong/demo/gin/internal/server/dto"
+)
+
+func newRespondRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID())
+	r.Use(gin.CustomRecovery(recovered))
+	r.NoRoute(NotFound)
+
+	r.GET("/ok", func(c *gin.Context) {
+		Respond(c, http.StatusOK, "found", map[string]int{"id": 1})
+	})
+	r.GET("/invalid", func(c *gin.Context) {
+		Abort(c, dto.Error{
+			Status:  http.StatusBadRequest,
+			Code:    dto.CodeValidationFailed,
+			Message: "validation failed",
+			Errors:  []dto.FieldError{{Field: "email", Message: "is required"}},
+		})
+	})
+	r.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	return r
+}
+
+func get(r *gin.Engine, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRespond(t *testing.T) {
+	setConfig(t, func(cfg *config.Config) {})
+	r := newRespondRouter()
+
+	t.Run("data", func(t *testing.T) {
+		w := get(r, "/ok")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"data":{"id":1},"message":"found","request_id":"req-1"}`, w.Body.String())
+	})
+
+	t.Run("error envelope", func(t *testing.T) {
+		w := get(r, "/invalid")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"code":"validation_failed","message":"validation failed",`+
+			`"errors":[{"field":"email","message":"is required"}],"request_id":"req-1"}`, w.Body.String())
+	})
+
+	t.Run("unknown route", func(t *testing.T) {
+		w := get(r, "/nope")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.JSONEq(t, `{"code":"not_found","message":"resource not found","request_id":"req-1"}`, w.Body.String())
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		w := get(r, "/panic")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.JSONEq(t, `{"code":"internal_error","message":"internal server error","request_id":"req-1"}`, w.Body.String())
+	})
+}
+
+func TestAbort_Problem(t *testing.T) {
+	r := newRespondRouter()
+
+	t.Run("about:blank", func(t *testing.T) {
+		setConfig(t, func(cfg *config.Config) { cfg.Server.ErrorFormat = "problem" })
+
+		w := get(r, "/invalid")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"validation failed",`+
+			`"instance":"/invalid","code":"validation_failed","errors":[{"field":"email","message":"is required"}],`+
+			`"request_id":"req-1"}`, w.Body.String())
+	})
+
+	t.Run("type base", func(t *testing.T) {
+		setConfig(t, func(cfg *config.Config) {
+			cfg.Server.ErrorFormat = "problem"
+			cfg.Server.ProblemTypeBase = "https://errors.example.com/"
+		})
+
+		w := get(r, "/nope")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), `"type":"https://errors.example.com/not_found"`)
+		assert.Contains(t, w.Body.String(), `"title":"Not Found"`)
+	})
+
+	t.Run("successful responses keep the envelope", func(t *testing.T) {
+		setConfig(t, func(cfg *config.Config) { cfg.Server.ErrorFormat = "problem" })
+
+		w := get(r, "/ok")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"data":{"id":1}`)
+	})
+}
@@ -0,0 +1,55 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OwnerFunc returns the ID of the user a request acts on, or false when the
+// request names none
+type OwnerFunc func(c *gin.Context) (string, bool)
+
+// Param reads the owner ID from a path parameter such as ":id"
+func Param(name string) OwnerFunc {
+	return func(c *gin.Context) (string, bool) {
+		id := c.Param(name)
+		return id, id != ""
+	}
+}
+
+// JSONField reads the owner ID from a top level field of the JSON body. The
+// body is restored afterwards so the handler can still bind it.
+func JSONField(name string) OwnerFunc {
+	return func(c *gin.Context) (string, bool) {
+		if c.Request.Body == nil {
+			return "", false
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return "", false
+		}
+
+		var fields map[string]any
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		if dec.Decode(&fields) != nil {
+			return "", false
+		}
+
+		switch v := fields[name].(type) {
+		case json.Number:
+			return v.String(), true
+		case string:
+			return v, v != ""
+		case nil:
+			return "", false
+		default:
+			return fmt.Sprint(v), true
+		}
+	}
+}
@@ -1,12 +1,44 @@
 package router
 
-import "github.com/gin-gonic/gin"
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yizhinailong/demo/gin/internal/model"
+)
 
 type RouteRegistrar interface {
 	RegisterRoutes(r *gin.Engine)
 }
 
-var registrars []RouteRegistrar
+// Rule declares what a caller needs to access one route. Path is the full
+// gin route template, e.g. "/users/:id".
+type Rule struct {
+	Method string
+	Path   string
+	// Scopes must all be present in the caller's token
+	Scopes []string
+	// Roles lists the roles allowed on the route; any one of them suffices
+	Roles []model.Role
+	// Owner extracts the ID of the user the request acts on. When it equals
+	// the caller's subject the request is allowed regardless of Roles.
+	Owner OwnerFunc
+	// Database returns the database the owner ID refers to. Subjects are IDs
+	// in auth.users.database, so the owner match only counts there.
+	Database func(c *gin.Context) string
+}
+
+// RuleProvider is implemented by registrars whose routes require
+// authentication; routes without a rule stay anonymous.
+type RuleProvider interface {
+	Rules() []Rule
+}
+
+var (
+	registrars []RouteRegistrar
+	rules      = map[string]Rule{}
+)
 
 func Register(registrar RouteRegistrar) {
 	registrars = append(registrars, registrar)
@@ -15,5 +47,35 @@ func Register(registrar RouteRegistrar) {
 func SetupRoutes(r *gin.Engine) {
 	for _, reg := range registrars {
 		reg.RegisterRoutes(r)
+		if p, ok := reg.(RuleProvider); ok {
+			AddRules(r, p.Rules()...)
+		}
+	}
+}
+
+// AddRules records access rules for routes already registered on r. A rule
+// for an unknown route panics, since a typo would leave the route open.
+func AddRules(r *gin.Engine, list ...Rule) {
+	for _, rule := range list {
+		if !hasRoute(r, rule.Method, rule.Path) {
+			panic(fmt.Sprintf("router: rule for unregistered route %s %s", rule.Method, rule.Path))
+		}
+		rules[rule.Method+" "+rule.Path] = rule
+	}
+}
+
+// RuleFor returns the rule declared for a route, matched by method and
+// c.FullPath()
+func RuleFor(method, path string) (Rule, bool) {
+	rule, ok := rules[method+" "+path]
+	return rule, ok
+}
+
+func hasRoute(r *gin.Engine, method, path string) bool {
+	for _, route := range r.Routes() {
+		if route.Method == method && route.Path == path {
+			return true
+		}
 	}
+	return false
 }
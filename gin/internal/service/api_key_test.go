@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yizhinailong/demo/gin/internal/auth"
+	"github.com/yizhinailong/demo/gin/internal/model"
+	"github.com/yizhinailong/demo/gin/internal/repository"
+)
+
+// MockAPIKeyRepository is a mock implementation of APIKeyRepository
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*model.APIKey, error) {
+	args := m.Called(ctx, prefix)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) List(ctx context.Context) ([]*model.APIKey, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) Revoke(ctx context.Context, id int64, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func newTestAPIKeyService(repo *MockAPIKeyRepository, now time.Time) *APIKeyService {
+	return &APIKeyService{
+		mysqlRepo:    repo,
+		postgresRepo: repo,
+		now:          func() time.Time { return now },
+	}
+}
+
+func TestAPIKeyService_IssueAndAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	mockRepo := new(MockAPIKeyRepository)
+	service := newTestAPIKeyService(mockRepo, now)
+
+	var stored *model.APIKey
+	mockRepo.On("Create", ctx, mock.AnythingOfType("*model.APIKey")).
+		Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*model.APIKey)
+			stored.ID = 7
+		}).
+		Return(nil).Once()
+
+	issued, err := service.Issue(ctx, &IssueAPIKeyInput{
+		Name:      "billing",
+		Scopes:    []string{"users:read", "users:write"},
+		ExpiresAt: now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	// dk_<prefix>_<secret>; only the salted hash is persisted
+	parts := strings.SplitN(issued.Key, "_", 3)
+	require.Len(t, parts, 3)
+	assert.Equal(t, "dk", parts[0])
+	assert.Equal(t, stored.Prefix, parts[1])
+	assert.NotContains(t, stored.Hash, parts[2])
+	assert.NotEmpty(t, stored.Salt)
+	assert.Equal(t, "users:read users:write", stored.Scopes)
+
+	mockRepo.On("GetByPrefix", ctx, stored.Prefix).Return(stored, nil)
+
+	t.Run("valid key", func(t *testing.T) {
+		mockRepo.On("TouchLastUsed", ctx, int64(7), now).Return(nil).Once()
+
+		p, err := service.AuthenticateKey(ctx, issued.Key)
+		require.NoError(t, err)
+		assert.Equal(t, "api_key:7", p.Subject)
+		assert.Equal(t, []string{"users:read", "users:write"}, p.Scopes)
+	})
+
+	t.Run("recently used key is not touched again", func(t *testing.T) {
+		stored.LastUsedAt = now.Add(-10 * time.Second)
+		defer func() { stored.LastUsedAt = time.Time{} }()
+
+		_, err := service.AuthenticateKey(ctx, issued.Key)
+		assert.NoError(t, err)
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		_, err := service.AuthenticateKey(ctx, "dk_"+stored.Prefix+"_wrong")
+		assert.ErrorIs(t, err, auth.ErrInvalidAPIKey)
+	})
+
+	t.Run("expired key", func(t *testing.T) {
+		expired := newTestAPIKeyService(mockRepo, now.Add(2*time.Hour))
+		_, err := expired.AuthenticateKey(ctx, issued.Key)
+		assert.ErrorIs(t, err, auth.ErrInvalidAPIKey)
+	})
+
+	t.Run("revoked key", func(t *testing.T) {
+		stored.RevokedAt = now
+		defer func() { stored.RevokedAt = time.Time{} }()
+
+		_, err := service.AuthenticateKey(ctx, issued.Key)
+		assert.ErrorIs(t, err, auth.ErrInvalidAPIKey)
+	})
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAPIKeyService_AuthenticateKey_Errors(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockAPIKeyRepository)
+	service := newTestAPIKeyService(mockRepo, time.Now())
+
+	for _, key := range []string{"", "dk_only", "xx_abc_def", "dk__secret"} {
+		_, err := service.AuthenticateKey(ctx, key)
+		assert.ErrorIs(t, err, auth.ErrInvalidAPIKey, key)
+	}
+
+	mockRepo.On("GetByPrefix", ctx, "unknown").Return(nil, fmt.Errorf("查询 API key 失败: %w", repository.ErrNotFound)).Once()
+	_, err := service.AuthenticateKey(ctx, "dk_unknown_secret")
+	assert.ErrorIs(t, err, auth.ErrInvalidAPIKey)
+
+	// Database failures are not reported as invalid keys
+	mockRepo.On("GetByPrefix", ctx, "down").Return(nil, assert.AnError).Once()
+	_, err = service.AuthenticateKey(ctx, "dk_down_secret")
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NotErrorIs(t, err, auth.ErrInvalidAPIKey)
+}
+
+func TestAPIKeyService_Issue_Validation(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	service := newTestAPIKeyService(new(MockAPIKeyRepository), now)
+
+	_, err := service.Issue(ctx, &IssueAPIKeyInput{Name: " "})
+	assert.Error(t, err)
+
+	_, err = service.Issue(ctx, &IssueAPIKeyInput{Name: "a", Scopes: []string{"two words"}})
+	assert.Error(t, err)
+
+	_, err = service.Issue(ctx, &IssueAPIKeyInput{Name: "a", ExpiresAt: now.Add(-time.Second)})
+	assert.Error(t, err)
+}
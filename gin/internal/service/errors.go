@@ -0,0 +1,41 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError describes one invalid input field
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError reports every invalid field of a service input
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, fe := range e.Fields {
+		msgs[i] = fe.Error()
+	}
+	return "参数校验失败: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) addf(field, format string, args ...any) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns e, or nil when no field was rejected
+func (e *ValidationError) err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
@@ -2,30 +2,68 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"go.uber.org/zap"
+
+	"github.com/yizhinailong/demo/gin/internal/auth"
+	"github.com/yizhinailong/demo/gin/internal/config"
 	"github.com/yizhinailong/demo/gin/internal/model"
 	"github.com/yizhinailong/demo/gin/internal/repository"
+	"github.com/yizhinailong/demo/gin/pkg/logger"
 )
 
 // UserServiceInterface defines the interface for user service operations
 type UserServiceInterface interface {
 	GetUser(ctx context.Context, input *GetUserInput) (*model.User, error)
 	CreateUser(ctx context.Context, input *CreateUserInput) (*model.User, error)
+	UpdateUser(ctx context.Context, input *UpdateUserInput) (*model.User, error)
+	DeleteUser(ctx context.Context, input *DeleteUserInput) error
+	ListUsers(ctx context.Context, input *ListUsersInput) (*UserList, error)
 }
 
+// ErrRoleChangeForbidden is returned when a caller other than an admin tries
+// to change a user's role, or to create a user with a role above viewer
+var ErrRoleChangeForbidden = errors.New("only admins may change roles")
+
+// DefaultDatabase is used when an input names no database
+const DefaultDatabase = "mysql"
+
+// Page sizes of ListUsers
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
 type UserService struct {
 	mysqlRepo    repository.UserRepository
 	postgresRepo repository.UserRepository
-	cache        sync.Map
+	// cache holds *model.User by cacheKey; the same ID names different users
+	// in different databases
+	cache sync.Map
+	// invalidations counts cache invalidations, so a read that raced a write
+	// does not leave the row it loaded before the write in the cache
+	invalidations atomic.Uint64
+}
+
+type cacheKey struct {
+	database string
+	id       int64
 }
 
 // CreateUserInput 创建用户输入（与 model 分离）
 type CreateUserInput struct {
 	Name     string
 	Email    string
+	Role     string
 	Database string
 }
 
@@ -34,6 +72,47 @@ type GetUserInput struct {
 	Database string
 }
 
+// UpdateUserInput 更新用户输入；nil 字段保持不变
+type UpdateUserInput struct {
+	ID       int64
+	Name     *string
+	Email    *string
+	Role     *string
+	Database string
+}
+
+type DeleteUserInput struct {
+	ID       int64
+	Database string
+}
+
+// ListUsersInput 用户列表查询输入
+type ListUsersInput struct {
+	Database string
+	// Limit defaults to DefaultListLimit and may not exceed MaxListLimit
+	Limit  int
+	Offset int
+	// Cursor is a UserList.NextCursor of an earlier call with the same Sort;
+	// it replaces Offset
+	Cursor string
+	// Sort is id, name or created_at, prefixed with "-" for descending order
+	Sort        string
+	NamePrefix  string
+	EmailDomain string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	CountTotal  bool
+}
+
+// UserList is one page of ListUsers
+type UserList struct {
+	Users []*model.User
+	// NextCursor fetches the following page; empty on the last one
+	NextCursor string
+	// Total counts all matching users, -1 unless CountTotal was set
+	Total int
+}
+
 func NewUserService() *UserService {
 	return &UserService{
 		mysqlRepo:    repository.NewUserMySQLRepository(),
@@ -41,6 +120,14 @@ func NewUserService() *UserService {
 	}
 }
 
+var defaultUserService = sync.OnceValue(NewUserService)
+
+// DefaultUserService returns the instance shared by the user handler and the
+// authorization middleware
+func DefaultUserService() *UserService {
+	return defaultUserService()
+}
+
 func (s *UserService) getUserRepo(dbType string) repository.UserRepository {
 	switch dbType {
 	case "mysql":
@@ -56,11 +143,13 @@ func (s *UserService) getUserRepo(dbType string) repository.UserRepository {
 func (s *UserService) GetUser(ctx context.Context, input *GetUserInput) (*model.User, error) {
 	dbType := input.Database
 	if dbType == "" {
-		dbType = "mysql"
+		dbType = DefaultDatabase
 	}
 
 	// 1. 先查缓存
-	if cached, ok := s.cache.Load(input.ID); ok {
+	key := cacheKey{database: dbType, id: input.ID}
+	if cached, ok := s.cache.Load(key); ok {
+		logger.FromContext(ctx).Named("service").Debug("User cache hit", zap.Int64("user_id", input.ID))
 		return cached.(*model.User), nil
 	}
 
@@ -70,17 +159,36 @@ func (s *UserService) GetUser(ctx context.Context, input *GetUserInput) (*model.
 		return nil, fmt.Errorf("database connection not available for %s", dbType)
 	}
 
+	seen := s.invalidations.Load()
 	user, err := repo.GetByID(ctx, input.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	// 3. 写入缓存
-	s.cache.Store(input.ID, user)
+	// 3. 写入缓存；期间有写入发生则撤回，读到的可能是旧数据
+	s.cache.Store(key, user)
+	if s.invalidations.Load() != seen {
+		s.cache.Delete(key)
+	}
 
 	return user, nil
 }
 
+// invalidate drops the cached user. Writers call it before and after the
+// write: before, so the old row is no longer served while the write runs,
+// and after, so a read that loaded the old row in between cannot keep it.
+func (s *UserService) invalidate(key cacheKey) {
+	s.invalidations.Add(1)
+	s.cache.Delete(key)
+}
+
+func validateName(name string) error {
+	if len(name) < 3 {
+		return fmt.Errorf("用户名长度至少3个字符")
+	}
+	return nil
+}
+
 func validateEmail(email string) error {
 	if email == "" {
 		return fmt.Errorf("邮箱不能为空")
@@ -104,22 +212,40 @@ func validateEmail(email string) error {
 func (s *UserService) CreateUser(ctx context.Context, input *CreateUserInput) (*model.User, error) {
 	dbType := input.Database
 	if dbType == "" {
-		dbType = "mysql"
+		dbType = DefaultDatabase
 	}
 
 	// 1. 业务验证：邮箱格式、用户名长度等
+	var verr ValidationError
 	if err := validateEmail(input.Email); err != nil {
-		return nil, fmt.Errorf("邮箱验证失败: %w", err)
+		verr.addf("email", "邮箱验证失败: %v", err)
+	}
+
+	if err := validateName(input.Name); err != nil {
+		verr.addf("name", "%v", err)
+	}
+
+	role, err := model.ParseRole(input.Role)
+	if err != nil {
+		verr.addf("role", "%v", err)
+	}
+
+	if err := verr.err(); err != nil {
+		return nil, err
 	}
 
-	if len(input.Name) < 3 {
-		return nil, fmt.Errorf("用户名长度至少3个字符")
+	// 创建即授予角色，与修改角色同样只允许管理员
+	if role != model.RoleViewer {
+		if err := s.requireAdmin(ctx); err != nil {
+			return nil, err
+		}
 	}
 
 	// 2. 构造模型
 	user := &model.User{
 		Name:  input.Name,
 		Email: input.Email,
+		Role:  role,
 	}
 
 	// 3. 调用 Repository 持久化
@@ -132,6 +258,243 @@ func (s *UserService) CreateUser(ctx context.Context, input *CreateUserInput) (*
 		return nil, fmt.Errorf("创建用户失败: %w", err)
 	}
 
+	logger.FromContext(ctx).Named("service").Info("User created",
+		zap.Int64("user_id", user.ID),
+		zap.String("database", dbType),
+	)
+
 	// 4. 返回结果（ID 已由 Repository 填充）
 	return user, nil
 }
+
+// UpdateUser applies the non-nil fields of input with the same validation as
+// CreateUser. Changing the role requires an admin caller whenever the request
+// is authenticated.
+func (s *UserService) UpdateUser(ctx context.Context, input *UpdateUserInput) (*model.User, error) {
+	dbType := input.Database
+	if dbType == "" {
+		dbType = DefaultDatabase
+	}
+
+	// 1. 业务验证
+	var verr ValidationError
+	if input.Email != nil {
+		if err := validateEmail(*input.Email); err != nil {
+			verr.addf("email", "邮箱验证失败: %v", err)
+		}
+	}
+
+	if input.Name != nil {
+		if err := validateName(*input.Name); err != nil {
+			verr.addf("name", "%v", err)
+		}
+	}
+
+	var role model.Role
+	if input.Role != nil {
+		var err error
+		if role, err = model.ParseRole(*input.Role); err != nil {
+			verr.addf("role", "%v", err)
+		}
+	}
+
+	if err := verr.err(); err != nil {
+		return nil, err
+	}
+
+	if input.Role != nil {
+		if err := s.requireAdmin(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	repo := s.getUserRepo(dbType)
+	if repo == nil {
+		return nil, fmt.Errorf("database connection not available for %s", dbType)
+	}
+
+	key := cacheKey{database: dbType, id: input.ID}
+	s.invalidate(key)
+
+	// 2. 读取当前记录再合并修改，避免整行更新时覆盖未提交的字段
+	user, err := repo.GetByID(ctx, input.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != nil {
+		user.Name = *input.Name
+	}
+	if input.Email != nil {
+		user.Email = *input.Email
+	}
+	if input.Role != nil {
+		user.Role = role
+	}
+	user.UpdatedAt = time.Now()
+
+	err = repo.Update(ctx, user)
+	// 3. 使缓存失效；失败时数据库状态未知，同样失效
+	s.invalidate(key)
+	if err != nil {
+		return nil, fmt.Errorf("更新用户失败: %w", err)
+	}
+
+	logger.FromContext(ctx).Named("service").Info("User updated",
+		zap.Int64("user_id", user.ID),
+		zap.String("database", dbType),
+	)
+
+	return user, nil
+}
+
+// DeleteUser removes a user and its cache entry
+func (s *UserService) DeleteUser(ctx context.Context, input *DeleteUserInput) error {
+	dbType := input.Database
+	if dbType == "" {
+		dbType = DefaultDatabase
+	}
+
+	repo := s.getUserRepo(dbType)
+	if repo == nil {
+		return fmt.Errorf("database connection not available for %s", dbType)
+	}
+
+	key := cacheKey{database: dbType, id: input.ID}
+	s.invalidate(key)
+	err := repo.Delete(ctx, input.ID)
+	s.invalidate(key)
+	if err != nil {
+		return fmt.Errorf("删除用户失败: %w", err)
+	}
+
+	logger.FromContext(ctx).Named("service").Info("User deleted",
+		zap.Int64("user_id", input.ID),
+		zap.String("database", dbType),
+	)
+
+	return nil
+}
+
+// ListUsers returns one page of users of the selected database, filtered
+// and sorted in SQL
+func (s *UserService) ListUsers(ctx context.Context, input *ListUsersInput) (*UserList, error) {
+	dbType := input.Database
+	if dbType == "" {
+		dbType = DefaultDatabase
+	}
+
+	opts, err := listOptions(input)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := s.getUserRepo(dbType)
+	if repo == nil {
+		return nil, fmt.Errorf("database connection not available for %s", dbType)
+	}
+
+	page, err := repo.List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &UserList{Users: page.Users, Total: page.Total}
+	if page.Next != nil {
+		list.NextCursor = page.Next.Encode()
+	}
+	return list, nil
+}
+
+// listOptions validates input and converts it to repository options
+func listOptions(input *ListUsersInput) (repository.UserListOptions, error) {
+	opts := repository.UserListOptions{
+		Limit:       input.Limit,
+		Offset:      input.Offset,
+		NamePrefix:  input.NamePrefix,
+		EmailDomain: input.EmailDomain,
+		CreatedFrom: input.CreatedFrom,
+		CreatedTo:   input.CreatedTo,
+		CountTotal:  input.CountTotal,
+	}
+
+	var verr ValidationError
+	switch {
+	case opts.Limit == 0:
+		opts.Limit = DefaultListLimit
+	case opts.Limit < 0 || opts.Limit > MaxListLimit:
+		verr.addf("limit", "must be between 1 and %d", MaxListLimit)
+	}
+	if opts.Offset < 0 {
+		verr.addf("offset", "must not be negative")
+	}
+
+	opts.Sort, opts.Desc = strings.CutPrefix(input.Sort, "-")
+	if opts.Sort == "" {
+		opts.Sort = repository.UserSortID
+	}
+	if !slices.Contains(repository.UserSorts, opts.Sort) {
+		verr.addf("sort", "must be one of %s", strings.Join(repository.UserSorts, ", "))
+	}
+
+	if !opts.CreatedFrom.IsZero() && !opts.CreatedTo.IsZero() && !opts.CreatedFrom.Before(opts.CreatedTo) {
+		verr.addf("created_to", "must be after created_from")
+	}
+
+	if input.Cursor != "" {
+		cursor, err := repository.DecodeCursor(input.Cursor)
+		if err != nil || cursor.Sort != opts.Sort || cursor.Desc != opts.Desc {
+			verr.addf("cursor", "does not belong to this sort order")
+		}
+		opts.After = cursor
+		opts.Offset = 0
+	}
+
+	if err := verr.err(); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// requireAdmin fails unless the authenticated caller, if any, is an admin
+func (s *UserService) requireAdmin(ctx context.Context) error {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	role, err := s.RoleOf(ctx, p)
+	if err != nil {
+		return err
+	}
+	if role != model.RoleAdmin {
+		return ErrRoleChangeForbidden
+	}
+	return nil
+}
+
+// RoleOf returns the role of the user whose ID is the principal's subject,
+// looked up in auth.users.database. Principals that are not users, such as
+// API keys, and unknown users have no role. The lookup bypasses the cache so
+// a demotion takes effect on the next request.
+func (s *UserService) RoleOf(ctx context.Context, p *auth.Principal) (model.Role, error) {
+	id, err := strconv.ParseInt(p.Subject, 10, 64)
+	if p.APIKey || err != nil {
+		return "", nil
+	}
+
+	database := config.GetConfig().Auth.Users.Database
+	repo := s.getUserRepo(database)
+	if repo == nil {
+		return "", fmt.Errorf("database connection not available for %s", database)
+	}
+
+	user, err := repo.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return user.Role, nil
+}
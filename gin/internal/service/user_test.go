@@ -2,11 +2,16 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/yizhinailong/demo/gin/internal/auth"
+	"github.com/yizhinailong/demo/gin/internal/config"
 	"github.com/yizhinailong/demo/gin/internal/model"
+	"github.com/yizhinailong/demo/gin/internal/repository"
 )
 
 // MockUserRepository is a mock implementation of UserRepository
@@ -37,12 +42,12 @@ func (m *MockUserRepository) Delete(ctx context.Context, id int64) error {
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) List(ctx context.Context) ([]*model.User, error) {
-	args := m.Called(ctx)
+func (m *MockUserRepository) List(ctx context.Context, opts repository.UserListOptions) (*repository.UserPage, error) {
+	args := m.Called(ctx, opts)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).([]*model.User), args.Error(1)
+	return args.Get(0).(*repository.UserPage), args.Error(1)
 }
 
 func TestNewUserService(t *testing.T) {
@@ -87,7 +92,7 @@ func TestUserService_GetUser(t *testing.T) {
 		}
 
 		// Pre-populate cache
-		service.cache.Store(int64(1), expectedUser)
+		service.cache.Store(cacheKey{database: "mysql", id: 1}, expectedUser)
 
 		input := &GetUserInput{
 			ID:       1,
@@ -99,6 +104,18 @@ func TestUserService_GetUser(t *testing.T) {
 		assert.Equal(t, expectedUser, user)
 	})
 
+	t.Run("cache is kept per database", func(t *testing.T) {
+		ctx := context.Background()
+		service.cache.Store(cacheKey{database: "mysql", id: 4}, &model.User{ID: 4, Name: "mysql user"})
+
+		pgUser := &model.User{ID: 4, Name: "postgres user"}
+		mockRepo.On("GetByID", ctx, int64(4)).Return(pgUser, nil).Once()
+
+		user, err := service.GetUser(ctx, &GetUserInput{ID: 4, Database: "postgres"})
+		assert.NoError(t, err)
+		assert.Equal(t, pgUser, user)
+	})
+
 	t.Run("get user from database", func(t *testing.T) {
 		ctx := context.Background()
 		expectedUser := &model.User{
@@ -170,7 +187,7 @@ func TestUserService_CreateUser(t *testing.T) {
 			Database: "mysql",
 		}
 
-		mockRepo.On("Create", ctx, mock.AnythingOfType("*model.User")).Return(nil)
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*model.User")).Return(nil).Once()
 
 		user, err := service.CreateUser(ctx, input)
 		assert.NoError(t, err)
@@ -206,6 +223,26 @@ func TestUserService_CreateUser(t *testing.T) {
 		assert.Contains(t, err.Error(), "用户名长度至少3个字符")
 	})
 
+	t.Run("create user reports every invalid field", func(t *testing.T) {
+		ctx := context.Background()
+		input := &CreateUserInput{
+			Name:     "ab",
+			Email:    "invalid-email",
+			Role:     "root",
+			Database: "mysql",
+		}
+
+		_, err := service.CreateUser(ctx, input)
+		var verr *ValidationError
+		if assert.ErrorAs(t, err, &verr) {
+			fields := make([]string, len(verr.Fields))
+			for i, fe := range verr.Fields {
+				fields[i] = fe.Field
+			}
+			assert.Equal(t, []string{"email", "name", "role"}, fields)
+		}
+	})
+
 	t.Run("create user with database error", func(t *testing.T) {
 		ctx := context.Background()
 		input := &CreateUserInput{
@@ -214,10 +251,342 @@ func TestUserService_CreateUser(t *testing.T) {
 			Database: "mysql",
 		}
 
-		mockRepo.On("Create", ctx, mock.AnythingOfType("*model.User")).Return(assert.AnError)
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*model.User")).Return(assert.AnError).Once()
 
 		_, err := service.CreateUser(ctx, input)
 		assert.Error(t, err)
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestUserService_CreateUser_Role(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockUserRepository)
+	service := &UserService{mysqlRepo: mockRepo}
+
+	mockRepo.On("Create", ctx, mock.MatchedBy(func(u *model.User) bool {
+		return u.Role == model.RoleViewer
+	})).Return(nil).Once()
+	mockRepo.On("Create", ctx, mock.MatchedBy(func(u *model.User) bool {
+		return u.Role == model.RoleOperator
+	})).Return(nil).Once()
+
+	user, err := service.CreateUser(ctx, &CreateUserInput{Name: "viewer", Email: "v@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleViewer, user.Role)
+
+	user, err = service.CreateUser(ctx, &CreateUserInput{Name: "operator", Email: "o@example.com", Role: "operator"})
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleOperator, user.Role)
+
+	_, err = service.CreateUser(ctx, &CreateUserInput{Name: "root", Email: "r@example.com", Role: "root"})
+	assert.Error(t, err)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_CreateUser_RoleRequiresAdmin(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := &UserService{mysqlRepo: mockRepo, postgresRepo: mockRepo}
+
+	t.Run("api key cannot create an admin", func(t *testing.T) {
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "api_key:1", APIKey: true, Scopes: []string{"users:write"}})
+
+		_, err := service.CreateUser(ctx, &CreateUserInput{Name: "root", Email: "r@example.com", Role: "admin"})
+		assert.ErrorIs(t, err, ErrRoleChangeForbidden)
+	})
+
+	t.Run("operator cannot create an operator", func(t *testing.T) {
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "5"})
+		mockRepo.On("GetByID", ctx, int64(5)).Return(&model.User{ID: 5, Role: model.RoleOperator}, nil).Once()
+
+		_, err := service.CreateUser(ctx, &CreateUserInput{Name: "operator", Email: "o@example.com", Role: "operator"})
+		assert.ErrorIs(t, err, ErrRoleChangeForbidden)
+	})
+
+	t.Run("api key creates a viewer", func(t *testing.T) {
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "api_key:1", APIKey: true, Scopes: []string{"users:write"}})
+		mockRepo.On("Create", ctx, mock.MatchedBy(func(u *model.User) bool {
+			return u.Role == model.RoleViewer
+		})).Return(nil).Once()
+
+		user, err := service.CreateUser(ctx, &CreateUserInput{Name: "viewer", Email: "v@example.com"})
+		assert.NoError(t, err)
+		assert.Equal(t, model.RoleViewer, user.Role)
+	})
+
+	t.Run("admin creates an admin", func(t *testing.T) {
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "6"})
+		mockRepo.On("GetByID", ctx, int64(6)).Return(&model.User{ID: 6, Role: model.RoleAdmin}, nil).Once()
+		mockRepo.On("Create", ctx, mock.MatchedBy(func(u *model.User) bool {
+			return u.Role == model.RoleAdmin
+		})).Return(nil).Once()
+
+		user, err := service.CreateUser(ctx, &CreateUserInput{Name: "admin", Email: "a@example.com", Role: "admin"})
+		assert.NoError(t, err)
+		assert.Equal(t, model.RoleAdmin, user.Role)
+	})
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_RoleOf(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockUserRepository)
+	service := &UserService{mysqlRepo: mockRepo, postgresRepo: mockRepo}
+
+	mockRepo.On("GetByID", ctx, int64(1)).Return(&model.User{ID: 1, Role: model.RoleAdmin}, nil).Once()
+	mockRepo.On("GetByID", ctx, int64(2)).Return(nil, fmt.Errorf("查询用户失败: %w", repository.ErrNotFound)).Once()
+	mockRepo.On("GetByID", ctx, int64(3)).Return(nil, assert.AnError).Once()
+
+	// A cached, stale record is ignored
+	service.cache.Store(cacheKey{database: "mysql", id: 1}, &model.User{ID: 1, Role: model.RoleViewer})
+
+	role, err := service.RoleOf(ctx, &auth.Principal{Subject: "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleAdmin, role)
+
+	role, err = service.RoleOf(ctx, &auth.Principal{Subject: "2"})
+	assert.NoError(t, err)
+	assert.Empty(t, role)
+
+	_, err = service.RoleOf(ctx, &auth.Principal{Subject: "3"})
+	assert.ErrorIs(t, err, assert.AnError)
+
+	role, err = service.RoleOf(ctx, &auth.Principal{Subject: "api_key:7"})
+	assert.NoError(t, err)
+	assert.Empty(t, role)
+
+	// API key principals have no user record, even if the subject parses
+	role, err = service.RoleOf(ctx, &auth.Principal{Subject: "1", APIKey: true})
+	assert.NoError(t, err)
+	assert.Empty(t, role)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_RoleOf_UsersDatabase(t *testing.T) {
+	ctx := context.Background()
+	mysqlRepo := new(MockUserRepository)
+	postgresRepo := new(MockUserRepository)
+	service := &UserService{mysqlRepo: mysqlRepo, postgresRepo: postgresRepo}
+
+	prev := config.GetConfig()
+	cfg := *prev
+	cfg.Auth.Users.Database = "postgres"
+	config.Set(&cfg)
+	t.Cleanup(func() { config.Set(prev) })
+
+	postgresRepo.On("GetByID", ctx, int64(5)).Return(&model.User{ID: 5, Role: model.RoleViewer}, nil).Once()
+
+	role, err := service.RoleOf(ctx, &auth.Principal{Subject: "5"})
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleViewer, role)
+
+	postgresRepo.AssertExpectations(t)
+	mysqlRepo.AssertNotCalled(t, "GetByID", ctx, int64(5))
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestUserService_UpdateUser(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := &UserService{
+		mysqlRepo:    mockRepo,
+		postgresRepo: mockRepo,
+	}
+
+	t.Run("update user and invalidate cache", func(t *testing.T) {
+		ctx := context.Background()
+		stored := &model.User{ID: 1, Name: "testuser", Email: "test@example.com", Role: model.RoleViewer}
+		service.cache.Store(cacheKey{database: "mysql", id: 1}, stored)
+
+		mockRepo.On("GetByID", ctx, int64(1)).Return(stored, nil).Once()
+		mockRepo.On("Update", ctx, mock.MatchedBy(func(u *model.User) bool {
+			return u.Name == "renamed" && u.Email == "test@example.com" && !u.UpdatedAt.IsZero()
+		})).Return(nil).Once()
+
+		user, err := service.UpdateUser(ctx, &UpdateUserInput{ID: 1, Name: strPtr("renamed")})
+		assert.NoError(t, err)
+		assert.Equal(t, "renamed", user.Name)
+
+		_, cached := service.cache.Load(cacheKey{database: "mysql", id: 1})
+		assert.False(t, cached)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("update user with invalid email", func(t *testing.T) {
+		ctx := context.Background()
+		_, err := service.UpdateUser(ctx, &UpdateUserInput{ID: 1, Email: strPtr("invalid-email")})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "邮箱验证失败")
+	})
+
+	t.Run("update user with short username", func(t *testing.T) {
+		ctx := context.Background()
+		_, err := service.UpdateUser(ctx, &UpdateUserInput{ID: 1, Name: strPtr("ab")})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "用户名长度至少3个字符")
+	})
+
+	t.Run("update user with unknown role", func(t *testing.T) {
+		ctx := context.Background()
+		_, err := service.UpdateUser(ctx, &UpdateUserInput{ID: 1, Role: strPtr("root")})
+		assert.Error(t, err)
+	})
+
+	t.Run("role change by non-admin", func(t *testing.T) {
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "5"})
+		mockRepo.On("GetByID", ctx, int64(5)).Return(&model.User{ID: 5, Role: model.RoleOperator}, nil).Once()
+
+		_, err := service.UpdateUser(ctx, &UpdateUserInput{ID: 5, Role: strPtr("admin")})
+		assert.ErrorIs(t, err, ErrRoleChangeForbidden)
+	})
+
+	t.Run("role change by admin", func(t *testing.T) {
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "6"})
+		mockRepo.On("GetByID", ctx, int64(6)).Return(&model.User{ID: 6, Role: model.RoleAdmin}, nil).Once()
+
+		mockRepo.On("GetByID", ctx, int64(7)).Return(&model.User{ID: 7, Role: model.RoleViewer}, nil).Once()
+		mockRepo.On("Update", ctx, mock.MatchedBy(func(u *model.User) bool {
+			return u.Role == model.RoleOperator
+		})).Return(nil).Once()
+
+		user, err := service.UpdateUser(ctx, &UpdateUserInput{ID: 7, Role: strPtr("operator")})
+		assert.NoError(t, err)
+		assert.Equal(t, model.RoleOperator, user.Role)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("update non-existent user", func(t *testing.T) {
+		ctx := context.Background()
+		mockRepo.On("GetByID", ctx, int64(999)).Return(nil, assert.AnError).Once()
+
+		_, err := service.UpdateUser(ctx, &UpdateUserInput{ID: 999, Name: strPtr("renamed")})
+		assert.Error(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("update user with database error", func(t *testing.T) {
+		ctx := context.Background()
+		service.cache.Store(cacheKey{database: "mysql", id: 8}, &model.User{ID: 8})
+		mockRepo.On("GetByID", ctx, int64(8)).Return(&model.User{ID: 8, Name: "testuser"}, nil).Once()
+		mockRepo.On("Update", ctx, mock.AnythingOfType("*model.User")).Return(assert.AnError).Once()
+
+		_, err := service.UpdateUser(ctx, &UpdateUserInput{ID: 8, Name: strPtr("renamed")})
+		assert.Error(t, err)
+
+		// The record is dropped before the write; whether it landed is unknown
+		_, cached := service.cache.Load(cacheKey{database: "mysql", id: 8})
+		assert.False(t, cached)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("read racing the update does not cache the old row", func(t *testing.T) {
+		ctx := context.Background()
+		old := &model.User{ID: 9, Name: "old", Role: model.RoleAdmin}
+
+		// The reader loads the old row, then the update lands before it stores it
+		mockRepo.On("GetByID", ctx, int64(9)).Return(old, nil).Run(func(mock.Arguments) {
+			service.invalidate(cacheKey{database: "mysql", id: 9})
+		}).Once()
+
+		user, err := service.GetUser(ctx, &GetUserInput{ID: 9})
+		assert.NoError(t, err)
+		assert.Equal(t, old, user)
+
+		_, cached := service.cache.Load(cacheKey{database: "mysql", id: 9})
+		assert.False(t, cached)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestUserService_DeleteUser(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := &UserService{
+		mysqlRepo:    mockRepo,
+		postgresRepo: mockRepo,
+	}
+
+	t.Run("delete user and invalidate cache", func(t *testing.T) {
+		ctx := context.Background()
+		service.cache.Store(cacheKey{database: "mysql", id: 1}, &model.User{ID: 1})
+		service.cache.Store(cacheKey{database: "postgres", id: 1}, &model.User{ID: 1})
+		mockRepo.On("Delete", ctx, int64(1)).Return(nil).Once()
+
+		err := service.DeleteUser(ctx, &DeleteUserInput{ID: 1, Database: "postgres"})
+		assert.NoError(t, err)
+
+		_, cached := service.cache.Load(cacheKey{database: "postgres", id: 1})
+		assert.False(t, cached)
+		// The user with the same ID in MySQL is a different record
+		_, cached = service.cache.Load(cacheKey{database: "mysql", id: 1})
+		assert.True(t, cached)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("delete user with database error", func(t *testing.T) {
+		ctx := context.Background()
+		mockRepo.On("Delete", ctx, int64(2)).Return(assert.AnError).Once()
+
+		err := service.DeleteUser(ctx, &DeleteUserInput{ID: 2})
+		assert.ErrorIs(t, err, assert.AnError)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestUserService_ListUsers(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	service := &UserService{
+		mysqlRepo:    mockRepo,
+		postgresRepo: mockRepo,
+	}
+	ctx := context.Background()
+
+	t.Run("defaults", func(t *testing.T) {
+		users := []*model.User{{ID: 1}, {ID: 2}}
+		next := &repository.Cursor{Sort: "id", Value: "2", ID: 2}
+		mockRepo.On("List", ctx, repository.UserListOptions{Limit: DefaultListLimit, Sort: "id"}).
+			Return(&repository.UserPage{Users: users, Next: next, Total: -1}, nil).Once()
+
+		got, err := service.ListUsers(ctx, &ListUsersInput{Database: "mysql"})
+		assert.NoError(t, err)
+		assert.Equal(t, users, got.Users)
+		assert.Equal(t, next.Encode(), got.NextCursor)
+		assert.Equal(t, -1, got.Total)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("cursor replaces offset", func(t *testing.T) {
+		cursor := &repository.Cursor{Sort: "name", Desc: true, Value: "bob", ID: 2}
+		mockRepo.On("List", ctx, repository.UserListOptions{
+			Limit: 5, Sort: "name", Desc: true, After: cursor, NamePrefix: "b", CountTotal: true,
+		}).Return(&repository.UserPage{Total: 7}, nil).Once()
+
+		got, err := service.ListUsers(ctx, &ListUsersInput{
+			Limit: 5, Offset: 10, Sort: "-name", Cursor: cursor.Encode(), NamePrefix: "b", CountTotal: true,
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, got.NextCursor)
+		assert.Equal(t, 7, got.Total)
+		mockRepo.AssertExpectations(t)
+	})
+
+	invalid := map[string]*ListUsersInput{
+		"limit too large":             {Limit: MaxListLimit + 1},
+		"negative offset":             {Offset: -1},
+		"unknown sort":                {Sort: "email"},
+		"garbage cursor":              {Cursor: "not-a-cursor"},
+		"cursor for a different sort": {Sort: "created_at", Cursor: (&repository.Cursor{Sort: "id", Value: "1", ID: 1}).Encode()},
+		"empty created range":         {CreatedFrom: time.Unix(100, 0), CreatedTo: time.Unix(100, 0)},
+	}
+	for name, input := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := service.ListUsers(ctx, input)
+			var verr *ValidationError
+			assert.ErrorAs(t, err, &verr)
+		})
+	}
+}
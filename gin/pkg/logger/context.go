@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type ctxKey struct{}
+
+// ctxValue 保存随请求传递的日志字段
+type ctxValue struct {
+	requestID string
+	fields    []zap.Field
+}
+
+// WithRequestID 返回携带请求 ID 的 context，FromContext 会自动带上 request_id 字段
+func WithRequestID(ctx context.Context, id string) context.Context {
+	v := load(ctx)
+	v.requestID = id
+	v.fields = append(v.fields, zap.String("request_id", id))
+	return context.WithValue(ctx, ctxKey{}, v)
+}
+
+// WithFields 返回追加了日志字段的 context，例如路由、用户 ID
+func WithFields(ctx context.Context, fields ...zap.Field) context.Context {
+	v := load(ctx)
+	v.fields = append(v.fields, fields...)
+	return context.WithValue(ctx, ctxKey{}, v)
+}
+
+// RequestID 返回 context 中的请求 ID，没有时返回空字符串
+func RequestID(ctx context.Context) string {
+	if v, ok := ctx.Value(ctxKey{}).(*ctxValue); ok {
+		return v.requestID
+	}
+	return ""
+}
+
+// FromContext 返回带有 context 中日志字段（request_id、route 等）的 logger
+func FromContext(ctx context.Context) *zap.Logger {
+	if fields := contextFields(ctx); len(fields) > 0 {
+		return L.With(fields...)
+	}
+	return L
+}
+
+func contextFields(ctx context.Context) []zap.Field {
+	if ctx == nil {
+		return nil
+	}
+	if v, ok := ctx.Value(ctxKey{}).(*ctxValue); ok {
+		return v.fields
+	}
+	return nil
+}
+
+// load 复制父 context 中的值，避免修改共享的切片
+func load(ctx context.Context) *ctxValue {
+	v := &ctxValue{}
+	if parent, ok := ctx.Value(ctxKey{}).(*ctxValue); ok {
+		v.requestID = parent.requestID
+		v.fields = append([]zap.Field(nil), parent.fields...)
+	}
+	return v
+}
@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFromContext(t *testing.T) {
+	read := initFileLogger(t, "info")
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = WithFields(ctx, zap.String("route", "/users/get"))
+	assert.Equal(t, "req-1", RequestID(ctx))
+
+	FromContext(ctx).Info("from zap")
+	slog.New(NewSlogHandler(nil)).InfoContext(ctx, "from slog")
+	FromContext(context.Background()).Info("no fields")
+
+	lines := read()
+	require.Len(t, lines, 3)
+	for _, line := range lines[:2] {
+		assert.Equal(t, "req-1", line["request_id"])
+		assert.Equal(t, "/users/get", line["route"])
+	}
+	assert.NotContains(t, lines[2], "request_id")
+}
+
+func TestWithFields_DoesNotLeakIntoParent(t *testing.T) {
+	parent := WithRequestID(context.Background(), "req-1")
+	WithFields(parent, zap.String("route", "/a"))
+	child := WithFields(parent, zap.String("route", "/b"))
+
+	assert.Len(t, contextFields(parent), 1)
+	assert.Len(t, contextFields(child), 2)
+	assert.Equal(t, "/b", contextFields(child)[1].String)
+}
@@ -0,0 +1,170 @@
+package logger
+
+import (
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	// level 是全局日志级别，可在运行时调整
+	level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	// baseLevel 是配置给出的级别，临时调整到期后恢复为它
+	baseLevel = zapcore.InfoLevel
+
+	levelMu sync.RWMutex
+	// named 保存按 logger 名称覆盖的级别，例如 "repository"
+	named = map[string]zapcore.Level{}
+	// reverts 保存临时调整的恢复定时器，"" 表示全局级别
+	reverts = map[string]*revert{}
+)
+
+type revert struct {
+	timer *time.Timer
+	at    time.Time
+}
+
+// LevelState 描述当前的级别设置
+type LevelState struct {
+	Level     string               `json:"level"`
+	Loggers   map[string]string    `json:"loggers"`
+	RevertsAt map[string]time.Time `json:"reverts_at"`
+}
+
+// SetLevel 永久设置全局级别（例如配置热更新）；进行中的临时调整到期后恢复为该级别
+func SetLevel(l zapcore.Level) {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+
+	baseLevel = l
+	if _, ok := reverts[""]; !ok {
+		level.SetLevel(l)
+	}
+}
+
+// SetTemporaryLevel 设置全局（name 为空）或指定 logger 的级别，d 之后自动恢复
+func SetTemporaryLevel(name string, l zapcore.Level, d time.Duration) {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+
+	if name == "" {
+		level.SetLevel(l)
+	} else {
+		named[name] = l
+	}
+
+	if r, ok := reverts[name]; ok {
+		r.timer.Stop()
+	}
+	r := &revert{at: time.Now().Add(d)}
+	r.timer = time.AfterFunc(d, func() { expire(name, r) })
+	reverts[name] = r
+}
+
+// expire 仅在 r 仍是当前的临时调整时恢复，避免撤销之后新的调整
+func expire(name string, r *revert) {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+
+	if reverts[name] == r {
+		resetLocked(name)
+	}
+}
+
+// ResetLevel 撤销临时调整：全局恢复为配置级别，指定 logger 则移除覆盖
+func ResetLevel(name string) {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+
+	resetLocked(name)
+}
+
+func resetLocked(name string) {
+	if r, ok := reverts[name]; ok {
+		r.timer.Stop()
+		delete(reverts, name)
+	}
+
+	if name == "" {
+		level.SetLevel(baseLevel)
+	} else {
+		delete(named, name)
+	}
+}
+
+// Levels 返回当前的级别设置
+func Levels() LevelState {
+	levelMu.RLock()
+	defer levelMu.RUnlock()
+
+	state := LevelState{
+		Level:     level.Level().String(),
+		Loggers:   make(map[string]string, len(named)),
+		RevertsAt: make(map[string]time.Time, len(reverts)),
+	}
+	for name, l := range named {
+		state.Loggers[name] = l.String()
+	}
+	for name, r := range reverts {
+		if name == "" {
+			name = "*"
+		}
+		state.RevertsAt[name] = r.at
+	}
+	return state
+}
+
+// levelFor 返回 logger 名称适用的级别：最长匹配的覆盖优先，否则为全局级别
+func levelFor(loggerName string) zapcore.Level {
+	levelMu.RLock()
+	defer levelMu.RUnlock()
+
+	best, lvl := -1, level.Level()
+	for name, l := range named {
+		if (loggerName == name || strings.HasPrefix(loggerName, name+".")) && len(name) > best {
+			best, lvl = len(name), l
+		}
+	}
+	return lvl
+}
+
+// minLevel 返回全局和所有覆盖中最低的级别，用于快速判断
+func minLevel() zapcore.Level {
+	levelMu.RLock()
+	defer levelMu.RUnlock()
+
+	lvl := level.Level()
+	for l := range maps.Values(named) {
+		lvl = min(lvl, l)
+	}
+	return lvl
+}
+
+// levelCore 在写入前按 logger 名称过滤级别，底层 core 接受所有级别
+type levelCore struct {
+	zapcore.Core
+}
+
+func (c *levelCore) Enabled(l zapcore.Level) bool {
+	return l >= minLevel()
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields)}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level < levelFor(ent.LoggerName) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// Named 返回指定名称的子 logger，可通过 SetTemporaryLevel 单独调整级别
+func Named(name string) *zap.Logger {
+	return L.Named(name)
+}
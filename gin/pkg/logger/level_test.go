@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSetTemporaryLevel(t *testing.T) {
+	read := initFileLogger(t, "info")
+	t.Cleanup(func() {
+		ResetLevel("")
+		ResetLevel("repository")
+	})
+
+	SetTemporaryLevel("repository", zapcore.DebugLevel, time.Hour)
+
+	Named("repository").Debug("repository debug")
+	Named("repository.mysql").Debug("child debug")
+	Named("handler").Debug("handler debug")
+	L.Debug("root debug")
+
+	var messages []string
+	for _, line := range read() {
+		messages = append(messages, line["message"].(string))
+	}
+	assert.Equal(t, []string{"repository debug", "child debug"}, messages)
+
+	state := Levels()
+	assert.Equal(t, "info", state.Level)
+	assert.Equal(t, map[string]string{"repository": "debug"}, state.Loggers)
+	assert.Contains(t, state.RevertsAt, "repository")
+}
+
+func TestSetTemporaryLevel_Reverts(t *testing.T) {
+	initFileLogger(t, "warn")
+	t.Cleanup(func() { ResetLevel("") })
+
+	SetTemporaryLevel("", zapcore.DebugLevel, 50*time.Millisecond)
+	assert.True(t, L.Core().Enabled(zapcore.DebugLevel))
+	assert.Contains(t, Levels().RevertsAt, "*")
+
+	// A config reload during the override becomes the level to revert to
+	SetLevel(zapcore.ErrorLevel)
+	assert.Equal(t, "debug", Levels().Level)
+
+	require.Eventually(t, func() bool {
+		return Levels().Level == "error"
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Empty(t, Levels().RevertsAt)
+}
+
+func TestSetTemporaryLevel_Replace(t *testing.T) {
+	initFileLogger(t, "info")
+	t.Cleanup(func() { ResetLevel("") })
+
+	SetTemporaryLevel("", zapcore.DebugLevel, 20*time.Millisecond)
+	SetTemporaryLevel("", zapcore.WarnLevel, time.Hour)
+
+	// The first timer must not undo the newer override
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, "warn", Levels().Level)
+
+	ResetLevel("")
+	assert.Equal(t, "info", Levels().Level)
+}
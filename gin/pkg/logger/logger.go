@@ -3,56 +3,104 @@ package logger
 import (
 	"os"
 	"path/filepath"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-var L *zap.Logger
+// L is the process-wide logger; it discards everything until Init is called
+var L = zap.NewNop()
 
 type Config struct {
-	Level      string
-	Console    bool
-	FilePath   string
-	MaxSize    int
-	MaxBackups int
-	MaxAge     int
-	Compress   bool
+	Level   string
+	Console bool
+	// ConsoleFormat is "console" (human readable, default) or "json"
+	ConsoleFormat string
+	// ConsoleOutput is "stdout" (default) or "stderr"
+	ConsoleOutput string
+	FilePath      string
+	MaxSize       int
+	MaxBackups    int
+	MaxAge        int
+	Compress      bool
+	// RedactFields 追加需要整体脱敏的字段名片段，RedactPatterns 追加匹配敏感内容的正则
+	RedactFields   []string
+	RedactPatterns []string
+	// SamplingInitial 大于 0 时启用采样：每个 SamplingTick 内相同级别和消息的日志
+	// 先记录 SamplingInitial 条，之后每 SamplingThereafter 条记录一条
+	SamplingInitial    int
+	SamplingThereafter int
+	SamplingTick       time.Duration
+}
+
+// jsonEncoderConfig is shared by the file sink and JSON console output
+var jsonEncoderConfig = zapcore.EncoderConfig{
+	TimeKey:        "timestamp",
+	LevelKey:       "level",
+	NameKey:        "logger",
+	CallerKey:      "caller",
+	MessageKey:     "message",
+	StacktraceKey:  "stacktrace",
+	LineEnding:     zapcore.DefaultLineEnding,
+	EncodeLevel:    zapcore.LowercaseLevelEncoder,
+	EncodeTime:     zapcore.ISO8601TimeEncoder, // ISO8601标准时间
+	EncodeDuration: zapcore.SecondsDurationEncoder,
+	EncodeCaller:   zapcore.ShortCallerEncoder,
+	EncodeName:     zapcore.FullNameEncoder,
 }
 
 // Init 初始化日志（终端和文件不同格式）
 func Init(cfg *Config) error {
-	level, err := zapcore.ParseLevel(cfg.Level)
+	lvl, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		lvl = zapcore.InfoLevel
+	}
+	SetLevel(lvl)
+
+	r, err := newRedactor(cfg.RedactFields, cfg.RedactPatterns)
 	if err != nil {
-		level = zapcore.InfoLevel
+		return err
 	}
+	redact = r
 
 	var cores []zapcore.Core
 
 	// ==================== 终端输出（Console格式） ====================
 	if cfg.Console {
-		// 控制台编码器：人类可读，带颜色
-		consoleEncoder := zapcore.NewConsoleEncoder(zapcore.EncoderConfig{
-			TimeKey:          "T",
-			LevelKey:         "L",
-			NameKey:          "N",
-			CallerKey:        "C",
-			MessageKey:       "M",
-			StacktraceKey:    "S",
-			LineEnding:       zapcore.DefaultLineEnding,
-			EncodeLevel:      zapcore.CapitalColorLevelEncoder,                   // 带颜色的大写级别
-			EncodeTime:       zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05"), // 简洁时间
-			EncodeDuration:   zapcore.StringDurationEncoder,
-			EncodeCaller:     zapcore.ShortCallerEncoder,
-			EncodeName:       zapcore.FullNameEncoder,
-			ConsoleSeparator: " ", // 字段间用空格分隔
-		})
+		var consoleEncoder zapcore.Encoder
+		if cfg.ConsoleFormat == "json" {
+			// JSON编码器：容器环境下由日志采集器解析
+			consoleEncoder = zapcore.NewJSONEncoder(jsonEncoderConfig)
+		} else {
+			// 控制台编码器：人类可读，带颜色
+			consoleEncoder = zapcore.NewConsoleEncoder(zapcore.EncoderConfig{
+				TimeKey:          "T",
+				LevelKey:         "L",
+				NameKey:          "N",
+				CallerKey:        "C",
+				MessageKey:       "M",
+				StacktraceKey:    "S",
+				LineEnding:       zapcore.DefaultLineEnding,
+				EncodeLevel:      zapcore.CapitalColorLevelEncoder,                   // 带颜色的大写级别
+				EncodeTime:       zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05"), // 简洁时间
+				EncodeDuration:   zapcore.StringDurationEncoder,
+				EncodeCaller:     zapcore.ShortCallerEncoder,
+				EncodeName:       zapcore.FullNameEncoder,
+				ConsoleSeparator: " ", // 字段间用空格分隔
+			})
+		}
+
+		output := os.Stdout
+		if cfg.ConsoleOutput == "stderr" {
+			output = os.Stderr
+		}
 
 		cores = append(cores, zapcore.NewCore(
 			consoleEncoder,
-			zapcore.AddSync(os.Stdout),
-			level,
+			zapcore.AddSync(output),
+			zapcore.DebugLevel, // 级别由 levelCore 统一过滤
 		))
 	}
 
@@ -64,20 +112,7 @@ func Init(cfg *Config) error {
 		}
 
 		// JSON编码器：机器解析，字段完整
-		fileEncoder := zapcore.NewJSONEncoder(zapcore.EncoderConfig{
-			TimeKey:        "timestamp",
-			LevelKey:       "level",
-			NameKey:        "logger",
-			CallerKey:      "caller",
-			MessageKey:     "message",
-			StacktraceKey:  "stacktrace",
-			LineEnding:     zapcore.DefaultLineEnding,
-			EncodeLevel:    zapcore.LowercaseLevelEncoder,
-			EncodeTime:     zapcore.ISO8601TimeEncoder, // ISO8601标准时间
-			EncodeDuration: zapcore.SecondsDurationEncoder,
-			EncodeCaller:   zapcore.ShortCallerEncoder,
-			EncodeName:     zapcore.FullNameEncoder,
-		})
+		fileEncoder := zapcore.NewJSONEncoder(jsonEncoderConfig)
 
 		// 文件切割配置
 		lumberjackLogger := &lumberjack.Logger{
@@ -91,12 +126,21 @@ func Init(cfg *Config) error {
 		cores = append(cores, zapcore.NewCore(
 			fileEncoder,
 			zapcore.AddSync(lumberjackLogger),
-			level,
+			zapcore.DebugLevel,
 		))
 	}
 
 	// 创建核心
-	core := zapcore.NewTee(cores...)
+	var core zapcore.Core = &redactCore{Core: zapcore.NewTee(cores...), r: r}
+	// 采样放在脱敏之外，被丢弃的日志无需脱敏
+	if cfg.SamplingInitial > 0 {
+		tick := cfg.SamplingTick
+		if tick <= 0 {
+			tick = time.Second
+		}
+		core = zapcore.NewSamplerWithOptions(core, tick, cfg.SamplingInitial, cfg.SamplingThereafter)
+	}
+	core = &levelCore{Core: core}
 	L = zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
 	return nil
 }
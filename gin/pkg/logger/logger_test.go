@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInit_Sampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	prev := L
+	t.Cleanup(func() { L = prev })
+	require.NoError(t, Init(&Config{
+		Level:              "info",
+		FilePath:           path,
+		SamplingInitial:    2,
+		SamplingThereafter: 5,
+		SamplingTick:       time.Minute,
+	}))
+
+	for range 12 {
+		L.Info("repeated")
+	}
+	L.Info("different")
+
+	Sync()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	// 前 2 条，然后第 7、12 条
+	assert.Equal(t, 4, strings.Count(string(data), `"repeated"`))
+	assert.Equal(t, 1, strings.Count(string(data), `"different"`))
+}
@@ -0,0 +1,209 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Masked 替换被脱敏的值
+const Masked = "******"
+
+// defaultRedactFields 是始终脱敏的字段名片段（不区分大小写）
+var defaultRedactFields = []string{
+	"password", "passwd", "secret", "token", "authorization", "cookie", "api_key", "apikey", "email",
+}
+
+// defaultRedactPatterns 匹配消息和字符串值中的敏感内容；有分组时保留前面的分组
+var defaultRedactPatterns = []string{
+	// 邮箱
+	`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`,
+	// 请求转储中的凭据请求头（例如 RecoveryWithZap 输出的 request）
+	`(?im)^((?:authorization|proxy-authorization|cookie|set-cookie|x-api-key|x-admin-token):[ \t]*)[^\r\n]+`,
+	// Bearer/Basic 凭据；Basic 区分大小写，避免误伤 "basic validation" 之类的文本
+	`(\b(?:(?i:bearer)|Basic)\s+)[A-Za-z0-9\-._~+/]{8,}=*`,
+	// password=xxx、"password":"xxx"、token: xxx 等键值对
+	`(?i)((?:password|passwd|pwd|secret|token|api_key|apikey)["']?\s*[:=]\s*["']?)[^\s"'&,;}]+`,
+}
+
+// redactor 按字段名和正则对日志内容脱敏
+type redactor struct {
+	fields   []string
+	patterns []*regexp.Regexp
+}
+
+// redact 是当前生效的规则，Init 之前使用默认规则
+var redact = mustRedactor(nil, nil)
+
+func newRedactor(fields, patterns []string) (*redactor, error) {
+	r := &redactor{}
+	for _, f := range slices.Concat(defaultRedactFields, fields) {
+		if f = strings.ToLower(strings.TrimSpace(f)); f != "" {
+			r.fields = append(r.fields, f)
+		}
+	}
+	for _, p := range slices.Concat(defaultRedactPatterns, patterns) {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+func mustRedactor(fields, patterns []string) *redactor {
+	r, err := newRedactor(fields, patterns)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Redact 按当前规则对 s 脱敏，可用于日志以外的输出（例如响应中回显的请求头）
+func Redact(s string) string {
+	return redact.string(s)
+}
+
+// sensitive 判断字段名是否需要整体脱敏
+func (r *redactor) sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, f := range r.fields {
+		if strings.Contains(key, f) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) string(s string) string {
+	for _, re := range r.patterns {
+		if !re.MatchString(s) {
+			continue
+		}
+		if re.NumSubexp() > 0 {
+			s = re.ReplaceAllString(s, "${1}"+Masked)
+		} else {
+			s = re.ReplaceAllLiteralString(s, Masked)
+		}
+	}
+	return s
+}
+
+func (r *redactor) fieldsOf(fields []zapcore.Field) []zapcore.Field {
+	out := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		out[i] = r.field(f)
+	}
+	return out
+}
+
+func (r *redactor) field(f zapcore.Field) zapcore.Field {
+	switch f.Type {
+	case zapcore.NamespaceType, zapcore.SkipType:
+		return f
+	}
+	if r.sensitive(f.Key) {
+		return zap.String(f.Key, Masked)
+	}
+
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = r.string(f.String)
+		return f
+	case zapcore.ByteStringType:
+		return zap.String(f.Key, r.string(string(f.Interface.([]byte))))
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			return zap.String(f.Key, r.string(err.Error()))
+		}
+		return f
+	case zapcore.StringerType:
+		if s, ok := f.Interface.(fmt.Stringer); ok && s != nil {
+			return zap.String(f.Key, r.string(s.String()))
+		}
+		return f
+	case zapcore.ReflectType, zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.InlineMarshalerType:
+		// 复杂值先展开为 map/slice，再逐层脱敏
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		if f.Type == zapcore.InlineMarshalerType {
+			return zap.Inline(redactedObject(r.value(enc.Fields).(map[string]any)))
+		}
+		return zap.Any(f.Key, r.value(enc.Fields[f.Key]))
+	}
+	return f
+}
+
+// value 递归脱敏 map、slice 和字符串；其余类型通过 JSON 转换后处理
+func (r *redactor) value(v any) any {
+	switch v := v.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case string:
+		return r.string(v)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, val := range v {
+			if r.sensitive(k) {
+				out[k] = Masked
+			} else {
+				out[k] = r.value(val)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = r.value(val)
+		}
+		return out
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return r.string(fmt.Sprint(v))
+		}
+		var decoded any
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return r.string(string(data))
+		}
+		return r.value(decoded)
+	}
+}
+
+// redactedObject 将脱敏后的 map 重新作为内联字段输出
+type redactedObject map[string]any
+
+func (o redactedObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for k, v := range o {
+		zap.Any(k, v).AddTo(enc)
+	}
+	return nil
+}
+
+// redactCore 在写入前对消息和字段脱敏，保证所有输出（终端、文件）都不含敏感信息
+type redactCore struct {
+	zapcore.Core
+	r *redactor
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.r.fieldsOf(fields)), r: c.r}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.r.string(ent.Message)
+	return c.Core.Write(ent, c.r.fieldsOf(fields))
+}
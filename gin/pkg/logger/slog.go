@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// slogHandler 将 slog 的记录转发给 zap，使两者共用同一格式和输出
+type slogHandler struct {
+	logger *zap.Logger // 为 nil 时在每次调用时使用全局 L
+	name   string      // 使用全局 L 时的子 logger 名称
+	fields []zap.Field
+}
+
+// NewSlogHandler 返回由 zap 支撑的 slog.Handler；l 为 nil 时跟随全局 L，
+// 这样在 Init 之前创建的 slog.Logger 也能在 Init 之后生效
+func NewSlogHandler(l *zap.Logger) slog.Handler {
+	return &slogHandler{logger: l}
+}
+
+// Slog 返回名为 name 的 slog.Logger，跟随全局 L 并可按名称调整级别
+func Slog(name string) *slog.Logger {
+	return slog.New(&slogHandler{name: name})
+}
+
+func (h *slogHandler) zap() *zap.Logger {
+	if h.logger != nil {
+		return h.logger
+	}
+	if h.name != "" {
+		return L.Named(h.name)
+	}
+	return L
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.zap().Core().Enabled(zapLevel(level))
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	ce := h.zap().Check(zapLevel(r.Level), r.Message)
+	if ce == nil {
+		return nil
+	}
+
+	// 使用 slog 调用方的位置和时间，而不是本适配器的
+	ce.Time = r.Time
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ce.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+	}
+
+	// 带上 context 中的 request_id 等字段（slog.InfoContext 等）
+	ctxFields := contextFields(ctx)
+	fields := make([]zap.Field, 0, len(ctxFields)+len(h.fields)+r.NumAttrs())
+	fields = append(fields, ctxFields...)
+	fields = append(fields, h.fields...)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, a)
+		return true
+	})
+
+	ce.Write(fields...)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make([]zap.Field, 0, len(h.fields)+len(attrs))
+	fields = append(fields, h.fields...)
+	for _, a := range attrs {
+		fields = appendAttr(fields, a)
+	}
+	return &slogHandler{logger: h.logger, name: h.name, fields: fields}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	fields := make([]zap.Field, 0, len(h.fields)+1)
+	fields = append(fields, h.fields...)
+	fields = append(fields, zap.Namespace(name))
+	return &slogHandler{logger: h.logger, name: h.name, fields: fields}
+}
+
+func zapLevel(level slog.Level) zapcore.Level {
+	switch {
+	case level >= slog.LevelError:
+		return zapcore.ErrorLevel
+	case level >= slog.LevelWarn:
+		return zapcore.WarnLevel
+	case level >= slog.LevelInfo:
+		return zapcore.InfoLevel
+	default:
+		return zapcore.DebugLevel
+	}
+}
+
+func appendAttr(fields []zap.Field, a slog.Attr) []zap.Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return append(fields, zap.String(a.Key, a.Value.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(a.Key, a.Value.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(a.Key, a.Value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(a.Key, a.Value.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(a.Key, a.Value.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(a.Key, a.Value.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(a.Key, a.Value.Time()))
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		if a.Key == "" {
+			// 无名分组直接展开
+			for _, ga := range attrs {
+				fields = appendAttr(fields, ga)
+			}
+			return fields
+		}
+		return append(fields, zap.Object(a.Key, zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			for _, f := range appendAttr(nil, slog.Attr{Value: slog.GroupValue(attrs...)}) {
+				f.AddTo(enc)
+			}
+			return nil
+		})))
+	default:
+		if err, ok := a.Value.Any().(error); ok {
+			return append(fields, zap.NamedError(a.Key, err))
+		}
+		return append(fields, zap.Any(a.Key, a.Value.Any()))
+	}
+}
@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initFileLogger points L at a JSON file in a temp dir and returns a reader
+// for the decoded lines written so far
+func initFileLogger(t *testing.T, level string) func() []map[string]any {
+	t.Helper()
+	prev := L
+	t.Cleanup(func() { L = prev })
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, Init(&Config{Level: level, FilePath: path}))
+
+	return func() []map[string]any {
+		Sync()
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		var lines []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			if line == "" {
+				continue
+			}
+			var entry map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &entry))
+			lines = append(lines, entry)
+		}
+		return lines
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	read := initFileLogger(t, "info")
+	log := slog.New(NewSlogHandler(nil))
+
+	log.Debug("hidden")
+	log.With("component", "test").WithGroup("req").Info("handled",
+		"status", 200,
+		slog.Group("user", "id", 7),
+	)
+	log.Error("failed", "error", errors.New("boom"))
+
+	lines := read()
+	require.Len(t, lines, 2)
+
+	assert.Equal(t, "info", lines[0]["level"])
+	assert.Equal(t, "handled", lines[0]["message"])
+	assert.Equal(t, "test", lines[0]["component"])
+	req := lines[0]["req"].(map[string]any)
+	assert.Equal(t, float64(200), req["status"])
+	assert.Equal(t, float64(7), req["user"].(map[string]any)["id"])
+	assert.Contains(t, lines[0]["caller"], "slog_test.go")
+
+	assert.Equal(t, "error", lines[1]["level"])
+	assert.Equal(t, "boom", lines[1]["error"])
+}
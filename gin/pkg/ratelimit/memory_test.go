@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	limit := Every(60, time.Minute, 3) // 1 token/s, burst 3
+
+	for i := range 3 {
+		res, err := s.Take(ctx, "ip:1.2.3.4", limit)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 2-i, res.Remaining)
+	}
+
+	res, _ := s.Take(ctx, "ip:1.2.3.4", limit)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second, res.Reset)
+
+	// Other keys have their own bucket
+	res, _ = s.Take(ctx, "ip:5.6.7.8", limit)
+	assert.True(t, res.Allowed)
+
+	// Tokens refill over time, up to the burst
+	now = now.Add(1500 * time.Millisecond)
+	res, _ = s.Take(ctx, "ip:1.2.3.4", limit)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	now = now.Add(time.Hour)
+	res, _ = s.Take(ctx, "ip:1.2.3.4", limit)
+	assert.Equal(t, 2, res.Remaining)
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	limit := Every(10, time.Second, 10)
+	_, _ = s.Take(ctx, "a", limit)
+	_, _ = s.Take(ctx, "b", limit)
+	assert.Equal(t, 2, s.Len())
+
+	// Idle buckets are full again and dropped on the next sweep
+	now = now.Add(2 * sweepInterval)
+	_, _ = s.Take(ctx, "c", limit)
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestEvery(t *testing.T) {
+	assert.Equal(t, Limit{Rate: 2, Burst: 120}, Every(120, time.Minute, 0))
+	assert.Equal(t, Limit{Rate: 2, Burst: 10}, Every(120, time.Minute, 10))
+}
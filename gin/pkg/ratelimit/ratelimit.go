@@ -0,0 +1,81 @@
+// Package ratelimit 实现令牌桶限流，桶状态保存在可替换的 Store 中
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit 描述一个令牌桶：每秒补充 Rate 个令牌，最多累积 Burst 个
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Every 返回每 period 允许 requests 次、突发 burst 次的限制；burst 为 0 时等于 requests
+func Every(requests int, period time.Duration, burst int) Limit {
+	if burst <= 0 {
+		burst = requests
+	}
+	return Limit{Rate: float64(requests) / period.Seconds(), Burst: burst}
+}
+
+// Result 是一次 Take 的结果
+type Result struct {
+	Allowed bool
+	// Remaining 是本次之后桶中剩余的整数令牌数
+	Remaining int
+	// Reset 是桶重新装满所需的时间
+	Reset time.Duration
+	// RetryAfter 是被拒绝时下一个令牌可用前需要等待的时间
+	RetryAfter time.Duration
+}
+
+// Store 保存令牌桶。默认的 MemoryStore 只在单个进程内生效，
+// 多副本部署可实现基于 Redis 等共享存储的 Store 以共用同一限额
+type Store interface {
+	// Take 尝试从 key 对应的桶中取出一个令牌，桶不存在时按 limit 创建并装满
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// bucket 是令牌桶的状态，供 Store 实现复用
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// limit 是最近一次使用的限制，用于判断桶是否已补满
+	limit Limit
+}
+
+// full 报告桶在 now 时是否已补满
+func (b *bucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate >= float64(b.limit.Burst)
+}
+
+// take 先按经过的时间补充令牌再尝试取出一个
+func (b *bucket) take(now time.Time, limit Limit) Result {
+	burst := float64(limit.Burst)
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed*limit.Rate)
+	}
+	b.last = now
+	b.limit = limit
+
+	res := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else if limit.Rate > 0 {
+		res.RetryAfter = seconds((1 - b.tokens) / limit.Rate)
+	}
+
+	res.Remaining = int(b.tokens)
+	if limit.Rate > 0 {
+		res.Reset = seconds((burst - b.tokens) / limit.Rate)
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}